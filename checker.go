@@ -0,0 +1,179 @@
+package sqlz
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const mysqlTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// IgnoreChecker considers any two values equal.
+type IgnoreChecker struct{}
+
+func (c IgnoreChecker) Match(row int, col int, def ColumnDef) bool { return true }
+
+func (c IgnoreChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool { return true }
+
+// FloatChecker compares FLOAT and DOUBLE values, two values are considered equal if their difference is within either
+// the absolute tolerance or the relative tolerance.
+type FloatChecker struct {
+	AbsTol float64
+	RelTol float64
+}
+
+func (c FloatChecker) Match(row int, col int, def ColumnDef) bool {
+	return matchType(def, "FLOAT", "DOUBLE", "REAL")
+}
+
+func (c FloatChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	if eq, ok := equalNull(v1, v2); ok {
+		return eq
+	}
+	f1, err1 := strconv.ParseFloat(string(v1), 64)
+	f2, err2 := strconv.ParseFloat(string(v2), 64)
+	if err1 != nil || err2 != nil {
+		return bytes.Equal(v1, v2)
+	}
+	if f1 == f2 || math.IsNaN(f1) && math.IsNaN(f2) {
+		return true
+	}
+	delta := math.Abs(f1 - f2)
+	return delta <= math.Abs(c.AbsTol) || delta <= math.Abs(c.RelTol)*math.Max(math.Abs(f1), math.Abs(f2))
+}
+
+// DecimalChecker compares DECIMAL values numerically, thus trailing zeros are ignored.
+type DecimalChecker struct{}
+
+func (c DecimalChecker) Match(row int, col int, def ColumnDef) bool {
+	return matchType(def, "DECIMAL", "NUMERIC")
+}
+
+func (c DecimalChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	if eq, ok := equalNull(v1, v2); ok {
+		return eq
+	}
+	d1, ok1 := new(big.Rat).SetString(string(v1))
+	d2, ok2 := new(big.Rat).SetString(string(v2))
+	if !ok1 || !ok2 {
+		return bytes.Equal(v1, v2)
+	}
+	return d1.Cmp(d2) == 0
+}
+
+// TimeChecker compares DATETIME and TIMESTAMP values, two values are considered equal if their difference is within the
+// tolerance, which is useful when fractional seconds are truncated or rounded by one side.
+type TimeChecker struct {
+	Tolerance time.Duration
+}
+
+func (c TimeChecker) Match(row int, col int, def ColumnDef) bool {
+	return matchType(def, "DATETIME", "TIMESTAMP")
+}
+
+func (c TimeChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	if eq, ok := equalNull(v1, v2); ok {
+		return eq
+	}
+	t1, err1 := time.Parse(mysqlTimeLayout, string(v1))
+	t2, err2 := time.Parse(mysqlTimeLayout, string(v2))
+	if err1 != nil || err2 != nil {
+		return bytes.Equal(v1, v2)
+	}
+	delta := t1.Sub(t2)
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta <= c.Tolerance
+}
+
+// JSONChecker compares JSON values semantically, key order, whitespaces and number formats are ignored.
+type JSONChecker struct{}
+
+func (c JSONChecker) Match(row int, col int, def ColumnDef) bool {
+	return matchType(def, "JSON")
+}
+
+func (c JSONChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	if eq, ok := equalNull(v1, v2); ok {
+		return eq
+	}
+	j1, err1 := decodeJSON(v1)
+	j2, err2 := decodeJSON(v2)
+	if err1 != nil || err2 != nil {
+		return bytes.Equal(v1, v2)
+	}
+	return equalJSON(j1, j2)
+}
+
+func decodeJSON(raw []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func equalJSON(j1 interface{}, j2 interface{}) bool {
+	switch x1 := j1.(type) {
+	case map[string]interface{}:
+		x2, ok := j2.(map[string]interface{})
+		if !ok || len(x1) != len(x2) {
+			return false
+		}
+		for k, v1 := range x1 {
+			v2, ok := x2[k]
+			if !ok || !equalJSON(v1, v2) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		x2, ok := j2.([]interface{})
+		if !ok || len(x1) != len(x2) {
+			return false
+		}
+		for i := range x1 {
+			if !equalJSON(x1[i], x2[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		x2, ok := j2.(json.Number)
+		if !ok {
+			return false
+		}
+		n1, ok1 := new(big.Rat).SetString(x1.String())
+		n2, ok2 := new(big.Rat).SetString(x2.String())
+		if !ok1 || !ok2 {
+			return x1 == x2
+		}
+		return n1.Cmp(n2) == 0
+	default:
+		return j1 == j2
+	}
+}
+
+func matchType(def ColumnDef, types ...string) bool {
+	t := strings.TrimPrefix(strings.ToUpper(def.Type), "UNSIGNED ")
+	for _, x := range types {
+		if t == x {
+			return true
+		}
+	}
+	return false
+}
+
+func equalNull(v1 []byte, v2 []byte) (bool, bool) {
+	if v1 == nil || v2 == nil {
+		return v1 == nil && v2 == nil, true
+	}
+	return false, false
+}
@@ -0,0 +1,77 @@
+package sqlz
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeAwareCheckers(t *testing.T) {
+	type EqTest struct {
+		v1 interface{}
+		v2 interface{}
+		ok bool
+	}
+	for i, tt := range []struct {
+		checker ValueChecker
+		match   []string
+		nomatch []string
+		tests   []EqTest
+	}{
+		{checker: FloatChecker{AbsTol: 0.001, RelTol: 1e-6}, match: []string{"FLOAT", "DOUBLE", "UNSIGNED DOUBLE"}, nomatch: []string{"DECIMAL"}, tests: []EqTest{
+			{"3.14", "3.1400", true},
+			{"3.14", "3.1409", true},
+			{"3.14", "3.142", false},
+			{"1e10", "1.000001e10", true},
+			{"1e10", "1.00001e10", false},
+			{"NaN", "NaN", true},
+			{nil, nil, true},
+			{nil, "0", false},
+			{"foo", "foo", true},
+		}},
+		{checker: DecimalChecker{}, match: []string{"DECIMAL"}, nomatch: []string{"DOUBLE"}, tests: []EqTest{
+			{"1.50", "1.5", true},
+			{"-0.00", "0", true},
+			{"10", "10.000", true},
+			{"1.51", "1.5", false},
+			{"", nil, false},
+		}},
+		{checker: TimeChecker{Tolerance: time.Second}, match: []string{"DATETIME", "TIMESTAMP"}, nomatch: []string{"DATE"}, tests: []EqTest{
+			{"2021-01-01 00:00:00.999", "2021-01-01 00:00:00", true},
+			{"2021-01-01 00:00:00", "2021-01-01 00:00:01", true},
+			{"2021-01-01 00:00:00", "2021-01-01 00:00:01.000001", false},
+			{"0000-00-00 00:00:00", "0000-00-00 00:00:00", true},
+			{"0000-00-00 00:00:00", "0000-00-00 00:00:01", false},
+		}},
+		{checker: JSONChecker{}, match: []string{"JSON"}, nomatch: []string{"TEXT"}, tests: []EqTest{
+			{`{"a": 1, "b": [1.0, "x", null]}`, `{"b":[1,"x",null],"a":1.00}`, true},
+			{`{"a": 1e2}`, `{"a": 100}`, true},
+			{`{"a": 1}`, `{"a": "1"}`, false},
+			{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+			{`[1, 2]`, `[2, 1]`, false},
+			{`{`, `{`, true},
+		}},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			for _, typ := range tt.match {
+				require.True(t, tt.checker.Match(0, 0, ColumnDef{Type: typ}), typ)
+			}
+			for _, typ := range tt.nomatch {
+				require.False(t, tt.checker.Match(0, 0, ColumnDef{Type: typ}), typ)
+			}
+			for _, et := range tt.tests {
+				require.Equal(t, et.ok, tt.checker.Equal(testRawValue(et.v1), testRawValue(et.v2), ColumnDef{}), "%v <> %v", et.v1, et.v2)
+				require.Equal(t, et.ok, tt.checker.Equal(testRawValue(et.v2), testRawValue(et.v1), ColumnDef{}), "%v <> %v", et.v2, et.v1)
+			}
+		})
+	}
+}
+
+func testRawValue(v interface{}) []byte {
+	if v == nil {
+		return nil
+	}
+	return []byte(v.(string))
+}
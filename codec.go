@@ -0,0 +1,504 @@
+package sqlz
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// The binary format of encoded result sets is
+//
+//	magic "SQLZ" | version (1 byte) | codec (1 byte) | body (compressed by codec) | crc32 of the uncompressed body (4 bytes)
+//
+// where the body is a sequence of sections ended by a zero tag. Each section (and each field in a section) is encoded as
+// tag (uvarint) | length (uvarint) | payload, so that decoders skip unknown sections and fields written by newer
+// versions. Incompatible changes must bump the version.
+const (
+	formatMagic   = "SQLZ"
+	formatVersion = 1
+)
+
+const (
+	sectionEnd = iota
+	sectionColumns
+	sectionExec
+	sectionRows
+)
+
+const (
+	fieldName = iota + 1
+	fieldType
+	fieldLength
+	fieldPrecision
+	fieldScale
+	fieldNullable
+)
+
+const (
+	fieldRowsAffected = iota + 1
+	fieldLastInsertId
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+type Codec uint8
+
+const (
+	CodecNone Codec = iota
+	CodecGzip
+	CodecFlate
+)
+
+type EncodeOptions struct {
+	Codec Codec
+	// ChunkRows limits the number of rows per section, 1024 by default.
+	ChunkRows int
+}
+
+func (opts EncodeOptions) chunkRows() int {
+	if opts.ChunkRows <= 0 {
+		return 1024
+	}
+	return opts.ChunkRows
+}
+
+// EncodeWith writes the result set in the versioned binary format, which is readable by DecodeFrom.
+func (rs *ResultSet) EncodeWith(w io.Writer, opts EncodeOptions) error {
+	fw, err := newFrameWriter(w, opts.Codec)
+	if err != nil {
+		return err
+	}
+	if rs.IsExecResult() || rs.exec != (ExecResult{}) {
+		err = fw.writeExec(rs.exec)
+	}
+	if err == nil && !rs.IsExecResult() {
+		err = fw.writeColumns(rs.cols)
+		n := opts.chunkRows()
+		for i := 0; i < rs.NRows() && err == nil; i += n {
+			err = fw.writeRows(rs, i, minInt(i+n, rs.NRows()))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return fw.close()
+}
+
+type frameWriter struct {
+	bw  *bufio.Writer
+	zw  io.WriteCloser
+	crc hash.Hash32
+	buf []byte
+}
+
+func newFrameWriter(w io.Writer, codec Codec) (*frameWriter, error) {
+	fw := &frameWriter{bw: bufio.NewWriter(w), crc: crc32.NewIEEE()}
+	switch codec {
+	case CodecNone:
+		fw.zw = nopWriteCloser{fw.bw}
+	case CodecGzip:
+		fw.zw = gzip.NewWriter(fw.bw)
+	case CodecFlate:
+		fw.zw, _ = flate.NewWriter(fw.bw, flate.DefaultCompression)
+	default:
+		return nil, fmt.Errorf("%w: codec %d", ErrUnsupportedFormat, codec)
+	}
+	fw.bw.WriteString(formatMagic)
+	fw.bw.Write([]byte{formatVersion, byte(codec)})
+	return fw, nil
+}
+
+func (fw *frameWriter) write(p []byte) error {
+	fw.crc.Write(p)
+	_, err := fw.zw.Write(p)
+	return err
+}
+
+func (fw *frameWriter) writeSection(tag uint64, payload []byte) error {
+	fw.buf = appendUvarint(appendUvarint(fw.buf[:0], tag), uint64(len(payload)))
+	if err := fw.write(fw.buf); err != nil {
+		return err
+	}
+	return fw.write(payload)
+}
+
+func (fw *frameWriter) writeColumns(cols []ColumnDef) error {
+	payload := appendUvarint(nil, uint64(len(cols)))
+	for _, c := range cols {
+		var fields []byte
+		fields = appendField(fields, fieldName, []byte(c.Name))
+		fields = appendField(fields, fieldType, []byte(c.Type))
+		if c.HasLength {
+			fields = appendField(fields, fieldLength, appendVarint(nil, c.Length))
+		}
+		if c.HasPrecisionScale {
+			fields = appendField(fields, fieldPrecision, appendVarint(nil, c.Precision))
+			fields = appendField(fields, fieldScale, appendVarint(nil, c.Scale))
+		}
+		if c.HasNullable {
+			fields = appendField(fields, fieldNullable, []byte{boolByte(c.Nullable)})
+		}
+		payload = appendField(payload, 0, fields)
+	}
+	return fw.writeSection(sectionColumns, payload)
+}
+
+func (fw *frameWriter) writeExec(e ExecResult) error {
+	var payload []byte
+	if e.HasRowsAffected {
+		payload = appendField(payload, fieldRowsAffected, appendVarint(nil, e.RowsAffected))
+	}
+	if e.HasLastInsertId {
+		payload = appendField(payload, fieldLastInsertId, appendVarint(nil, e.LastInsertId))
+	}
+	return fw.writeSection(sectionExec, payload)
+}
+
+// writeRows writes rows in [from, to) of rs as a section, each cell is encoded as len<<1|isNull (uvarint) | value.
+func (fw *frameWriter) writeRows(rs *ResultSet, from int, to int) error {
+	payload := appendUvarint(nil, uint64(to-from))
+	for i := from; i < to; i++ {
+		for j := range rs.cols {
+			if rs.isNil(i, j) {
+				payload = appendUvarint(payload, 1)
+				continue
+			}
+			v, _ := rs.RawValue(i, j)
+			payload = append(appendUvarint(payload, uint64(len(v))<<1), v...)
+		}
+	}
+	return fw.writeSection(sectionRows, payload)
+}
+
+func (fw *frameWriter) close() error {
+	if err := fw.write([]byte{sectionEnd}); err != nil {
+		return err
+	}
+	if err := fw.zw.Close(); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], fw.crc.Sum32())
+	fw.bw.Write(sum[:])
+	return fw.bw.Flush()
+}
+
+// frameReader reads sections of the versioned binary format.
+type frameReader struct {
+	br         *bufio.Reader
+	r          *crcReader
+	compressed bool
+}
+
+func newFrameReader(br *bufio.Reader) (*frameReader, error) {
+	var hdr [len(formatMagic) + 2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+	if string(hdr[:len(formatMagic)]) != formatMagic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrUnsupportedFormat, hdr[:len(formatMagic)])
+	}
+	if v := hdr[len(formatMagic)]; v != formatVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedFormat, v)
+	}
+	fr := &frameReader{br: br, compressed: true}
+	switch codec := Codec(hdr[len(formatMagic)+1]); codec {
+	case CodecNone:
+		fr.r, fr.compressed = &crcReader{br, crc32.NewIEEE()}, false
+	case CodecGzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		zr.Multistream(false)
+		fr.r = &crcReader{bufio.NewReader(zr), crc32.NewIEEE()}
+	case CodecFlate:
+		fr.r = &crcReader{bufio.NewReader(flate.NewReader(br)), crc32.NewIEEE()}
+	default:
+		return nil, fmt.Errorf("%w: codec %d", ErrUnsupportedFormat, codec)
+	}
+	return fr, nil
+}
+
+// next returns the next section, the tag is sectionEnd at the end of body.
+func (fr *frameReader) next() (uint64, []byte, error) {
+	tag, err := binary.ReadUvarint(fr.r)
+	if err != nil || tag == sectionEnd {
+		return tag, nil, unexpectedEOF(err)
+	}
+	n, err := binary.ReadUvarint(fr.r)
+	if err != nil {
+		return tag, nil, unexpectedEOF(err)
+	}
+	payload, err := ioutil.ReadAll(io.LimitReader(fr.r, int64(n)))
+	if err == nil && uint64(len(payload)) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return tag, payload, err
+}
+
+// close verifies the checksum after the end section.
+func (fr *frameReader) close() error {
+	if fr.compressed {
+		if n, err := io.Copy(ioutil.Discard, fr.r); err != nil {
+			return err
+		} else if n > 0 {
+			return errors.New("unexpected data after end of body")
+		}
+	}
+	var sum [4]byte
+	if _, err := io.ReadFull(fr.br, sum[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if binary.BigEndian.Uint32(sum[:]) != fr.r.crc.Sum32() {
+		return errors.New("checksum mismatch")
+	}
+	return nil
+}
+
+// decode reads the result set from the versioned binary format.
+func (rs *ResultSet) decode(br *bufio.Reader) error {
+	fr, err := newFrameReader(br)
+	if err != nil {
+		return err
+	}
+	tmp := ResultSet{}
+	for {
+		tag, payload, err := fr.next()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case sectionEnd:
+			if err = fr.close(); err != nil {
+				return err
+			}
+			*rs = tmp
+			return nil
+		case sectionColumns:
+			tmp.cols, err = decodeColumns(payload)
+		case sectionExec:
+			tmp.exec, err = decodeExec(payload)
+		case sectionRows:
+			if tmp.cols == nil {
+				err = errRowsBeforeColumns
+			} else {
+				err = tmp.decodeRows(payload)
+			}
+		}
+		if err != nil {
+			return sectionError(tag, err)
+		}
+	}
+}
+
+var errRowsBeforeColumns = errors.New("rows before columns")
+
+func sectionError(tag uint64, err error) error {
+	if err == nil || tag == sectionEnd {
+		return err
+	}
+	return fmt.Errorf("invalid section#%d: %w", tag, err)
+}
+
+func decodeColumns(payload []byte) ([]ColumnDef, error) {
+	n, payload, err := readUvarint(payload)
+	if err != nil {
+		return nil, err
+	}
+	// bound the count by the payload before allocation, each column takes at least 2 bytes.
+	if n > uint64(len(payload)/2) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	cols := make([]ColumnDef, 0, n)
+	for len(payload) > 0 {
+		var fields []byte
+		if _, fields, payload, err = readField(payload); err != nil {
+			return nil, err
+		}
+		var c ColumnDef
+		for len(fields) > 0 {
+			var tag uint64
+			var v []byte
+			if tag, v, fields, err = readField(fields); err != nil {
+				return nil, err
+			}
+			switch tag {
+			case fieldName:
+				c.Name = string(v)
+			case fieldType:
+				c.Type = string(v)
+			case fieldLength:
+				c.Length, err = readVarint(v)
+				c.HasLength = true
+			case fieldPrecision:
+				c.Precision, err = readVarint(v)
+				c.HasPrecisionScale = true
+			case fieldScale:
+				c.Scale, err = readVarint(v)
+				c.HasPrecisionScale = true
+			case fieldNullable:
+				c.Nullable, c.HasNullable = len(v) > 0 && v[0] != 0, true
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		cols = append(cols, c)
+	}
+	if uint64(len(cols)) != n {
+		return nil, fmt.Errorf("expected %d cols, not %d", n, len(cols))
+	}
+	return cols, nil
+}
+
+func decodeExec(payload []byte) (ExecResult, error) {
+	var e ExecResult
+	for len(payload) > 0 {
+		tag, v, rest, err := readField(payload)
+		if err != nil {
+			return e, err
+		}
+		switch tag {
+		case fieldRowsAffected:
+			e.RowsAffected, err = readVarint(v)
+			e.HasRowsAffected = true
+		case fieldLastInsertId:
+			e.LastInsertId, err = readVarint(v)
+			e.HasLastInsertId = true
+		}
+		if err != nil {
+			return e, err
+		}
+		payload = rest
+	}
+	return e, nil
+}
+
+func (rs *ResultSet) decodeRows(payload []byte) error {
+	n, payload, err := readUvarint(payload)
+	if err != nil {
+		return err
+	}
+	// each row takes at least 1 byte per column, the check also bounds the loop for rows without columns.
+	if n > uint64(len(payload)) {
+		return io.ErrUnexpectedEOF
+	}
+	for k := uint64(0); k < n; k++ {
+		i, row := rs.NRows(), rs.AllocateRow()
+		for j := range row {
+			var x uint64
+			if x, payload, err = readUvarint(payload); err != nil {
+				return err
+			}
+			if x&1 == 1 {
+				rs.markNil(i, j)
+				continue
+			}
+			if uint64(len(payload)) < x>>1 {
+				return io.ErrUnexpectedEOF
+			}
+			*row[j].(*[]byte) = payload[:x>>1]
+			payload = payload[x>>1:]
+		}
+		rs.flush()
+	}
+	return nil
+}
+
+func appendField(buf []byte, tag uint64, v []byte) []byte {
+	return append(appendUvarint(appendUvarint(buf, tag), uint64(len(v))), v...)
+}
+
+func readField(buf []byte) (uint64, []byte, []byte, error) {
+	tag, buf, err := readUvarint(buf)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	n, buf, err := readUvarint(buf)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if uint64(len(buf)) < n {
+		return 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	return tag, buf[:n], buf[n:], nil
+}
+
+func readUvarint(buf []byte) (uint64, []byte, error) {
+	x, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return x, buf[n:], nil
+}
+
+func readVarint(buf []byte) (int64, error) {
+	x, n := binary.Varint(buf)
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return x, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], x)]...)
+}
+
+func appendVarint(buf []byte, x int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], x)]...)
+}
+
+// crcReader computes the checksum of read bytes.
+type crcReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (r *crcReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc.Write(p[:n])
+	return n, err
+}
+
+func (r *crcReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,83 @@
+package sqlz
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeWith(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, HasLength: true},
+		{Name: "price", Type: "DECIMAL", Precision: 10, Scale: -2, HasPrecisionScale: true},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", "foo", "1.50"},
+		[]interface{}{"2", nil, "0.00"},
+		[]interface{}{"3", "", nil},
+	)
+	exec := NewFromResult(execResult{ExecResult{RowsAffected: -1, HasRowsAffected: true}})
+	check := func(rs1 *ResultSet, rs2 *ResultSet) {
+		require.Equal(t, rs1.cols, rs2.cols)
+		require.Equal(t, rs1.exec, rs2.exec)
+		require.Equal(t, rs1.NRows(), rs2.NRows())
+		require.Equal(t, rs1.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+
+	var stream bytes.Buffer
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecFlate} {
+		for _, tt := range []*ResultSet{rs, New(cols), exec} {
+			var buf bytes.Buffer
+			require.NoError(t, tt.EncodeWith(&buf, EncodeOptions{Codec: codec, ChunkRows: 2}))
+			require.Equal(t, []byte{'S', 'Q', 'L', 'Z', 1, byte(codec)}, buf.Bytes()[:6])
+			stream.Write(buf.Bytes())
+			rs2 := &ResultSet{}
+			require.NoError(t, rs2.Decode(buf.Bytes()))
+			check(tt, rs2)
+
+			raw := buf.Bytes()
+			raw[len(raw)-1] ^= 0xff
+			require.EqualError(t, rs2.Decode(raw), "checksum mismatch")
+		}
+	}
+	br := bufio.NewReader(&stream)
+	for k := 0; k < 9; k++ {
+		rs2 := &ResultSet{}
+		require.NoError(t, rs2.DecodeFrom(br))
+		check([]*ResultSet{rs, New(cols), exec}[k%3], rs2)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, rs.encodeLegacy(&buf))
+	rs2 := &ResultSet{}
+	require.NoError(t, rs2.Decode(buf.Bytes()))
+	check(rs, rs2)
+
+	// sections and fields unknown to this version are skipped
+	buf.Reset()
+	fw, err := newFrameWriter(&buf, CodecGzip)
+	require.NoError(t, err)
+	require.NoError(t, fw.writeSection(99, []byte("future section")))
+	require.NoError(t, fw.writeSection(sectionColumns, appendField(appendUvarint(nil, 1), 0,
+		appendField(appendField(nil, fieldName, []byte("id")), 99, []byte("future field")))))
+	require.NoError(t, fw.writeRows(newTestResultSet([]ColumnDef{{Name: "id"}}, []interface{}{"1"}), 0, 1))
+	require.NoError(t, fw.close())
+	require.NoError(t, rs2.Decode(buf.Bytes()))
+	require.Equal(t, []ColumnDef{{Name: "id"}}, rs2.cols)
+	require.NoError(t, rs2.AssertData(Rows{{"1"}}))
+
+	raw := buf.Bytes()
+	raw[4] = 2
+	require.True(t, errors.Is(rs2.Decode(raw), ErrUnsupportedFormat))
+	require.EqualError(t, rs2.Decode([]byte("SQLZ\x01\x09")), "unsupported format: codec 9")
+	require.EqualError(t, rs2.Decode([]byte("PK\x03\x04")), `unsupported format: bad magic "PK\x03\x04"`)
+	require.EqualError(t, rs2.Decode([]byte("SQLZ\x01\x00\x03\x05\x01")), "unexpected EOF")
+	huge := "\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"
+	require.EqualError(t, rs2.Decode([]byte("SQLZ\x01\x00\x01\x0a"+huge)), "invalid section#1: unexpected EOF")
+	require.EqualError(t, (&ResultSet{}).decodeRows([]byte(huge)), "unexpected EOF")
+	require.Error(t, rs.EncodeWith(&buf, EncodeOptions{Codec: 9}))
+}
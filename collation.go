@@ -0,0 +1,112 @@
+package sqlz
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var stringTypes = []string{
+	"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET",
+}
+
+var accentFolding = func() map[rune]rune {
+	from := []rune("ÀÁÂÃÄÅàáâãäåÈÉÊËèéêëÌÍÎÏìíîïÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿÑñÇçŠšŽžŸ")
+	to := []rune("AAAAAAaaaaaaEEEEeeeeIIIIiiiiOOOOOOooooooUUUUuuuuYyyNnCcSsZzY")
+	m := make(map[rune]rune, len(from))
+	for i := range from {
+		m[from[i]] = to[i]
+	}
+	return m
+}()
+
+// Collation describes how strings are compared under a MySQL collation.
+type Collation struct {
+	Name              string
+	Binary            bool
+	CaseInsensitive   bool
+	AccentInsensitive bool
+	PadSpace          bool
+}
+
+// ParseCollation derives comparison rules from a collation name like binary, utf8mb4_bin, utf8mb4_general_ci,
+// utf8mb4_unicode_ci or utf8mb4_0900_as_ci.
+func ParseCollation(name string) Collation {
+	name = strings.ToLower(name)
+	c := Collation{Name: name}
+	if name == "binary" {
+		c.Binary = true
+		return c
+	}
+	c.PadSpace = !strings.Contains(name, "0900") && !strings.Contains(name, "nopad")
+	if strings.HasSuffix(name, "_ci") {
+		c.CaseInsensitive = true
+		c.AccentInsensitive = !strings.HasSuffix(name, "_as_ci")
+	}
+	return c
+}
+
+// Normalize maps a raw value to a form in which values equal under the collation are identical.
+func (c Collation) Normalize(raw []byte) []byte {
+	if raw == nil || c.Binary {
+		return raw
+	}
+	if c.PadSpace {
+		raw = bytes.TrimRight(raw, " ")
+	}
+	if !c.CaseInsensitive && !c.AccentInsensitive || !utf8.Valid(raw) {
+		return raw
+	}
+	out := make([]byte, 0, len(raw))
+	for _, r := range string(raw) {
+		if c.AccentInsensitive {
+			if x, ok := accentFolding[r]; ok {
+				r = x
+			}
+		}
+		if c.CaseInsensitive {
+			r = unicode.ToUpper(r)
+		}
+		out = append(out, string(r)...)
+	}
+	return out
+}
+
+// CollationChecker compares strings under a collation, which is usually built by ParseCollation. It matches columns
+// listed in Columns or having one of Types, all string columns are matched if neither is specified.
+type CollationChecker struct {
+	Collation Collation
+	Columns   []string
+	Types     []string
+}
+
+func (c CollationChecker) Match(row int, col int, def ColumnDef) bool {
+	if len(c.Columns) == 0 && len(c.Types) == 0 {
+		return matchType(def, stringTypes...)
+	}
+	for _, name := range c.Columns {
+		if strings.EqualFold(name, def.Name) {
+			return true
+		}
+	}
+	return matchType(def, c.Types...)
+}
+
+func (c CollationChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	if eq, ok := equalNull(v1, v2); ok {
+		return eq
+	}
+	return bytes.Equal(c.Collation.Normalize(v1), c.Collation.Normalize(v2))
+}
+
+// Mapper returns a function which can be used as DigestOptions.Mapper to normalize matched columns.
+func (c CollationChecker) Mapper() func(i int, j int, raw []byte, def ColumnDef) []byte {
+	coll := c.Collation
+	return func(i int, j int, raw []byte, def ColumnDef) []byte {
+		if !c.Match(i, j, def) {
+			return raw
+		}
+		return coll.Normalize(raw)
+	}
+}
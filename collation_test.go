@@ -0,0 +1,55 @@
+package sqlz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollationChecker(t *testing.T) {
+	require.Equal(t, len([]rune("ÀÁÂÃÄÅàáâãäåÈÉÊËèéêëÌÍÎÏìíîïÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿÑñÇçŠšŽžŸ")), len(accentFolding))
+
+	for _, tt := range []struct {
+		collation string
+		v1        interface{}
+		v2        interface{}
+		ok        bool
+	}{
+		{"binary", "abc", "abc", true},
+		{"binary", "abc", "abc ", false},
+		{"binary", "abc", "ABC", false},
+		{"utf8mb4_bin", "abc", "abc  ", true},
+		{"utf8mb4_bin", "abc", "ABC", false},
+		{"utf8mb4_general_ci", "abc", "ABC ", true},
+		{"utf8mb4_general_ci", "café", "CAFE", true},
+		{"utf8mb4_general_ci", " abc", "abc", false},
+		{"utf8mb4_unicode_ci", "Ärger", "arger", true},
+		{"utf8mb4_0900_ai_ci", "abc", "ABC", true},
+		{"utf8mb4_0900_ai_ci", "abc", "abc ", false},
+		{"utf8mb4_0900_as_ci", "abc", "ABC", true},
+		{"utf8mb4_0900_as_ci", "é", "E", false},
+		{"utf8mb4_0900_as_cs", "abc", "ABC", false},
+		{"utf8mb4_general_ci", nil, nil, true},
+		{"utf8mb4_general_ci", nil, "", false},
+	} {
+		c := CollationChecker{Collation: ParseCollation(tt.collation)}
+		require.Equal(t, tt.ok, c.Equal(testRawValue(tt.v1), testRawValue(tt.v2), ColumnDef{}), "%s: %v <> %v", tt.collation, tt.v1, tt.v2)
+	}
+
+	c := CollationChecker{Collation: ParseCollation("utf8mb4_general_ci")}
+	require.True(t, c.Match(0, 0, ColumnDef{Type: "VARCHAR"}))
+	require.False(t, c.Match(0, 0, ColumnDef{Type: "VARBINARY"}))
+	c = CollationChecker{Collation: ParseCollation("utf8mb4_general_ci"), Columns: []string{"name"}, Types: []string{"JSON"}}
+	require.True(t, c.Match(0, 0, ColumnDef{Name: "NAME", Type: "BLOB"}))
+	require.True(t, c.Match(0, 0, ColumnDef{Name: "doc", Type: "JSON"}))
+	require.False(t, c.Match(0, 0, ColumnDef{Name: "foo", Type: "VARCHAR"}))
+
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols, []interface{}{"1", "abc"}, []interface{}{"2", "Foo"})
+	rs2 := newTestResultSet(cols, []interface{}{"2", "FOO "}, []interface{}{"1", "ABC"})
+	opts := DiffOptions{Unordered: true, ValueCheckers: []ValueChecker{CollationChecker{Collation: ParseCollation("utf8mb4_general_ci")}, RawBytesChecker{}}}
+	require.NoError(t, Diff(rs1, rs2, opts))
+	mapper := DigestOptions{Sort: true, Mapper: CollationChecker{Collation: ParseCollation("utf8mb4_general_ci"), Columns: []string{"name"}}.Mapper()}
+	require.Equal(t, rs1.DataDigest(mapper), rs2.DataDigest(mapper))
+	require.NotEqual(t, rs1.DataDigest(DigestOptions{Sort: true}), rs2.DataDigest(DigestOptions{Sort: true}))
+}
@@ -0,0 +1,211 @@
+package sqlz
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' by default.
+	Comma rune
+	// Null is the token of NULL values. Only unquoted fields equal to it are NULL, so the default empty token reads
+	// empty unquoted fields as NULL and "" as the empty string.
+	Null string
+	// Header indicates the first record is the header of column names.
+	Header bool
+	// QuoteAll quotes all non-NULL values in WriteCSV.
+	QuoteAll bool
+}
+
+func (opts CSVOptions) comma() rune {
+	if opts.Comma == 0 {
+		return ','
+	}
+	return opts.Comma
+}
+
+// ReadCSV reads a result set from RFC 4180 CSV. Columns are defined by schema, or named by the header if schema is nil.
+// If both are given, names in schema are filled by the header when they are empty.
+func ReadCSV(r io.Reader, schema []ColumnDef, opts CSVOptions) (*ResultSet, error) {
+	cr := &csvReader{r: bufio.NewReader(r), comma: opts.comma(), line: 1}
+	cols := append([]ColumnDef{}, schema...)
+	if opts.Header {
+		hdr, err := cr.read(true)
+		if err == io.EOF {
+			return nil, errors.New("missing csv header")
+		} else if err != nil {
+			return nil, err
+		}
+		if schema == nil {
+			cols = make([]ColumnDef, len(hdr))
+		} else if len(hdr) != len(cols) {
+			return nil, fmt.Errorf("csv header mismatch: expected %d cols, not %d", len(cols), len(hdr))
+		}
+		for j, f := range hdr {
+			if len(cols[j].Name) == 0 {
+				cols[j].Name = f.s
+			}
+		}
+	}
+	if len(cols) == 0 {
+		return nil, errors.New("missing csv schema")
+	}
+	rs := New(cols)
+	// an empty line is a record of one column, which is either NULL or an empty string.
+	skipBlank := len(cols) > 1
+	for {
+		rec, err := cr.read(skipBlank)
+		if err == io.EOF {
+			return rs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(rec) != len(cols) {
+			return nil, fmt.Errorf("csv line#%d: expected %d fields, not %d", cr.start, len(cols), len(rec))
+		}
+		i, row := rs.NRows(), rs.AllocateRow()
+		for j, f := range rec {
+			if !f.quoted && f.s == opts.Null {
+				rs.markNil(i, j)
+			} else {
+				*row[j].(*[]byte) = []byte(f.s)
+			}
+		}
+		rs.flush()
+	}
+}
+
+// WriteCSV writes rows of the result set as RFC 4180 CSV, values equal to the NULL token are quoted to be told from
+// NULL.
+func WriteCSV(w io.Writer, rs *ResultSet, opts CSVOptions) error {
+	if rs.IsExecResult() {
+		return errors.New("can not write exec result as csv")
+	}
+	bw := bufio.NewWriter(w)
+	comma := string(opts.comma())
+	quote := func(s string) string {
+		if opts.QuoteAll {
+			return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+		}
+		return quoteCSV(s, opts.Null, opts.comma())
+	}
+	if opts.Header {
+		for j, c := range rs.cols {
+			if j > 0 {
+				bw.WriteString(comma)
+			}
+			bw.WriteString(quote(c.Name))
+		}
+		bw.WriteString("\r\n")
+	}
+	for i := 0; i < rs.NRows(); i++ {
+		for j := range rs.cols {
+			if j > 0 {
+				bw.WriteString(comma)
+			}
+			if rs.isNil(i, j) {
+				bw.WriteString(opts.Null)
+			} else {
+				v, _ := rs.RawValue(i, j)
+				bw.WriteString(quote(string(v)))
+			}
+		}
+		bw.WriteString("\r\n")
+	}
+	return bw.Flush()
+}
+
+type csvField struct {
+	s      string
+	quoted bool
+}
+
+// csvReader is like csv.Reader but reports whether fields are quoted, which is required to tell NULL from strings.
+type csvReader struct {
+	r     *bufio.Reader
+	comma rune
+	line  int
+	start int
+}
+
+// read returns the next record, empty lines are skipped if skipBlank is set.
+func (cr *csvReader) read(skipBlank bool) ([]csvField, error) {
+	for {
+		rec, err := cr.readRecord()
+		if err != nil || !skipBlank || len(rec) != 1 || rec[0].quoted || len(rec[0].s) > 0 {
+			return rec, err
+		}
+	}
+}
+
+func (cr *csvReader) readRecord() ([]csvField, error) {
+	var rec []csvField
+	var b strings.Builder
+	cr.start = cr.line
+	c, _, err := cr.r.ReadRune()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		b.Reset()
+		f := csvField{}
+		if c == '"' {
+			f.quoted = true
+			for {
+				if c, _, err = cr.r.ReadRune(); err == io.EOF {
+					return nil, fmt.Errorf("csv line#%d: unterminated quoted field", cr.start)
+				} else if err != nil {
+					return nil, err
+				}
+				if c == '\n' {
+					cr.line++
+				}
+				if c != '"' {
+					b.WriteRune(c)
+					continue
+				}
+				if c, _, err = cr.r.ReadRune(); err != nil || c != '"' {
+					break
+				}
+				b.WriteRune('"')
+			}
+			if c == '\r' && err == nil {
+				if c, _, err = cr.r.ReadRune(); err == nil && c != '\n' {
+					return nil, fmt.Errorf("csv line#%d: unexpected \\r after quoted field", cr.line)
+				}
+			}
+			if err == nil && c != cr.comma && c != '\n' {
+				return nil, fmt.Errorf("csv line#%d: unexpected %q after quoted field", cr.line, c)
+			}
+			f.s = b.String()
+		} else {
+			for err == nil && c != cr.comma && c != '\n' {
+				if c == '"' {
+					return nil, fmt.Errorf("csv line#%d: bare \" in unquoted field", cr.line)
+				}
+				b.WriteRune(c)
+				c, _, err = cr.r.ReadRune()
+			}
+			f.s = strings.TrimSuffix(b.String(), "\r")
+		}
+		rec = append(rec, f)
+		if err == io.EOF {
+			return rec, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if c == '\n' {
+			cr.line++
+			return rec, nil
+		}
+		if c, _, err = cr.r.ReadRune(); err == io.EOF {
+			// a trailing comma at the end of input
+			return append(rec, csvField{}), nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
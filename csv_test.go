@@ -0,0 +1,65 @@
+package sqlz
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}, {Name: "note", Type: "TEXT"}}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", nil, "a,b"},
+		[]interface{}{"2", "", `say "hi"`},
+		[]interface{}{"3", "NULL", "x\r\ny"},
+		[]interface{}{"4", `\N`, " z "},
+	)
+
+	for _, tt := range []struct {
+		opts   CSVOptions
+		expect string
+	}{
+		{CSVOptions{}, "1,,\"a,b\"\r\n2,\"\",\"say \"\"hi\"\"\"\r\n3,NULL,\"x\r\ny\"\r\n4,\\N,\" z \"\r\n"},
+		{CSVOptions{Null: `\N`, Header: true}, "id,name,note\r\n1,\\N,\"a,b\"\r\n2,,\"say \"\"hi\"\"\"\r\n3,NULL,\"x\r\ny\"\r\n4,\"\\N\",\" z \"\r\n"},
+		{CSVOptions{Null: "NULL", Comma: '\t', QuoteAll: true}, "\"1\"\tNULL\t\"a,b\"\r\n\"2\"\t\"\"\t\"say \"\"hi\"\"\"\r\n\"3\"\t\"NULL\"\t\"x\r\ny\"\r\n\"4\"\t\"\\N\"\t\" z \"\r\n"},
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(&buf, rs, tt.opts))
+		require.Equal(t, tt.expect, buf.String())
+		rs2, err := ReadCSV(&buf, cols, tt.opts)
+		require.NoError(t, err)
+		require.Equal(t, cols, rs2.cols)
+		require.NoError(t, Diff(rs, rs2, DiffOptions{CheckSchema: true}))
+		require.Equal(t, rs.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+
+	single := newTestResultSet(cols[:1], []interface{}{"x"}, []interface{}{nil}, []interface{}{""}, []interface{}{"y"})
+	for _, opts := range []CSVOptions{{}, {Null: `\N`}, {Header: true}} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(&buf, single, opts))
+		rs2, err := ReadCSV(&buf, cols[:1], opts)
+		require.NoError(t, err)
+		require.NoError(t, Diff(single, rs2, DiffOptions{}))
+		require.Equal(t, single.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+
+	_, err := ReadCSV(strings.NewReader("id,name\n1,\n\n2,b,"), nil, CSVOptions{Header: true, Null: ""})
+	require.EqualError(t, err, "csv line#4: expected 2 fields, not 3")
+	rs2, err := ReadCSV(strings.NewReader("id,name\n1,\n\n2,b\n"), []ColumnDef{{Type: "INT"}, {Name: "x"}}, CSVOptions{Header: true})
+	require.NoError(t, err)
+	require.Equal(t, []ColumnDef{{Name: "id", Type: "INT"}, {Name: "x"}}, rs2.cols)
+	require.NoError(t, rs2.AssertData(Rows{{"1", nil}, {"2", "b"}}))
+
+	_, err = ReadCSV(strings.NewReader("1,\"a\"b\n"), cols[:2], CSVOptions{})
+	require.EqualError(t, err, `csv line#1: unexpected 'b' after quoted field`)
+	_, err = ReadCSV(strings.NewReader("1,a\"b\n"), cols[:2], CSVOptions{})
+	require.EqualError(t, err, `csv line#1: bare " in unquoted field`)
+	_, err = ReadCSV(strings.NewReader("1,\"a\n"), cols[:2], CSVOptions{})
+	require.EqualError(t, err, "csv line#1: unterminated quoted field")
+	_, err = ReadCSV(strings.NewReader("1,a\n"), nil, CSVOptions{})
+	require.EqualError(t, err, "missing csv schema")
+	_, err = ReadCSV(strings.NewReader("id\n"), cols, CSVOptions{Header: true})
+	require.EqualError(t, err, "csv header mismatch: expected 3 cols, not 1")
+}
@@ -2,7 +2,11 @@ package sqlz
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type ValueChecker interface {
@@ -20,74 +24,663 @@ type DiffOptions struct {
 	CheckSchema    bool
 	CheckPrecision bool
 	ValueCheckers  []ValueChecker
+
+	// Unordered matches rows as a multiset instead of by position, which is useful for queries without ORDER BY.
+	Unordered bool
+	// KeyColumns and KeyIndexes identify key columns by name or by index, rows are aligned by their keys if any of them
+	// is specified.
+	KeyColumns []string
+	KeyIndexes []int
+
+	// AlignColumnsByName pairs columns by names instead of positions.
+	AlignColumnsByName bool
+	// IgnoreColumnCase makes column names case-insensitive, it applies to IgnoreColumns and KeyColumns as well.
+	IgnoreColumnCase bool
+	// IgnoreColumns excludes the named columns from comparison.
+	IgnoreColumns []string
+	// CommonColumnsOnly compares only the columns present on both sides instead of reporting the others as mismatches.
+	CommonColumnsOnly bool
+
+	// IgnoreLastInsertId compares exec results by affected rows only.
+	IgnoreLastInsertId bool
+	// LastInsertIdDelta is the max tolerated difference of last insert ids.
+	LastInsertIdDelta int64
+	// UnsupportedAsWildcard makes an unsupported RowsAffected or LastInsertId match any value.
+	UnsupportedAsWildcard bool
+}
+
+func (opts DiffOptions) checkers() []ValueChecker {
+	if opts.ValueCheckers == nil {
+		return []ValueChecker{RawBytesChecker{}}
+	}
+	return opts.ValueCheckers
+}
+
+func (opts DiffOptions) equalRowsAffected(e1 ExecResult, e2 ExecResult) bool {
+	if opts.UnsupportedAsWildcard && (!e1.HasRowsAffected || !e2.HasRowsAffected) {
+		return true
+	}
+	return e1.HasRowsAffected == e2.HasRowsAffected && e1.RowsAffected == e2.RowsAffected
+}
+
+func (opts DiffOptions) equalLastInsertId(e1 ExecResult, e2 ExecResult) bool {
+	if opts.IgnoreLastInsertId || opts.UnsupportedAsWildcard && (!e1.HasLastInsertId || !e2.HasLastInsertId) {
+		return true
+	}
+	if e1.HasLastInsertId != e2.HasLastInsertId {
+		return false
+	}
+	delta := e1.LastInsertId - e2.LastInsertId
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta <= opts.LastInsertIdDelta
+}
+
+func (opts DiffOptions) sameName(name1 string, name2 string) bool {
+	if opts.IgnoreColumnCase {
+		return strings.EqualFold(name1, name2)
+	}
+	return name1 == name2
+}
+
+func (opts DiffOptions) ignored(name string) bool {
+	for _, x := range opts.IgnoreColumns {
+		if opts.sameName(x, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaDiff describes a mismatched attribute of the col-th column.
+type SchemaDiff struct {
+	Col   int
+	Field string
+	Left  string
+	Right string
+}
+
+func (d SchemaDiff) String() string {
+	return fmt.Sprintf("cols[%d].%s: %s <> %s", d.Col, d.Field, d.Left, d.Right)
+}
+
+// CellDiff describes a pair of values rejected by the value checkers.
+type CellDiff struct {
+	Row       int
+	RightRow  int
+	Col       int
+	Name      string
+	Left      []byte
+	Right     []byte
+	LeftNull  bool
+	RightNull bool
+}
+
+func (d CellDiff) String() string {
+	return fmt.Sprintf("data mismatch (%q#%d): %v <> %v", d.Name, d.Row, d.Left, d.Right)
+}
+
+// DiffReport collects all differences between two result sets.
+type DiffReport struct {
+	TypeMismatch bool
+	ExecMismatch bool
+
+	LeftExec  ExecResult
+	RightExec ExecResult
+	LeftCols  int
+	RightCols int
+	LeftRows  int
+	RightRows int
+
+	Schema        []SchemaDiff
+	Cells         []CellDiff
+	LeftOnly      []int
+	RightOnly     []int
+	LeftOnlyCols  []int
+	RightOnlyCols []int
+
+	DiffStats
+
+	invalid    error
+	commonOnly bool
+}
+
+// DiffStats counts differences between two result sets, it can be aggregated over many pairs of result sets by Add.
+type DiffStats struct {
+	ComparedRows    int
+	ComparedCells   int
+	MismatchedRows  int
+	MismatchedCells int
+	LeftOnlyRows    int
+	RightOnlyRows   int
+	// UnmatchedCells counts the cells of compared columns in rows present on only one side.
+	UnmatchedCells int
+	// ColumnMismatches counts mismatched cells by column names.
+	ColumnMismatches map[string]int
+}
+
+func (s *DiffStats) Add(o DiffStats) {
+	s.ComparedRows += o.ComparedRows
+	s.ComparedCells += o.ComparedCells
+	s.MismatchedRows += o.MismatchedRows
+	s.MismatchedCells += o.MismatchedCells
+	s.LeftOnlyRows += o.LeftOnlyRows
+	s.RightOnlyRows += o.RightOnlyRows
+	s.UnmatchedCells += o.UnmatchedCells
+	for name, cnt := range o.ColumnMismatches {
+		s.countColumnMismatch(name, cnt)
+	}
+}
+
+// Similarity returns the ratio of matched cells to all cells involved, it's 1 if nothing is compared.
+func (s DiffStats) Similarity() float64 {
+	total := s.ComparedCells + s.UnmatchedCells
+	if total == 0 {
+		return 1
+	}
+	return float64(s.ComparedCells-s.MismatchedCells) / float64(total)
+}
+
+func (s *DiffStats) countColumnMismatch(name string, cnt int) {
+	if s.ColumnMismatches == nil {
+		s.ColumnMismatches = make(map[string]int)
+	}
+	s.ColumnMismatches[name] += cnt
+}
+
+func (r *DiffReport) Equal() bool { return r.Err() == nil }
+
+// Err summarizes the first difference found in the report, it returns nil if there is no difference.
+func (r *DiffReport) Err() error {
+	if r.invalid != nil {
+		return r.invalid
+	}
+	if r.TypeMismatch {
+		return fmt.Errorf("result type mismatch: %s <> %s",
+			describeResult(r.LeftCols, r.LeftRows, r.LeftExec), describeResult(r.RightCols, r.RightRows, r.RightExec))
+	}
+	if r.ExecMismatch {
+		return fmt.Errorf("execute result mismatch: %v <> %v", r.LeftExec, r.RightExec)
+	}
+	if !r.commonOnly && (len(r.LeftOnlyCols) > 0 || len(r.RightOnlyCols) > 0) {
+		if r.LeftCols != r.RightCols {
+			return fmt.Errorf("col count mismatch: %d <> %d", r.LeftCols, r.RightCols)
+		}
+		return fmt.Errorf("col mismatch: %v <> %v", r.LeftOnlyCols, r.RightOnlyCols)
+	}
+	if r.LeftRows != r.RightRows {
+		return fmt.Errorf("row count mismatch: %d <> %d", r.LeftRows, r.RightRows)
+	}
+	if len(r.Schema) > 0 {
+		return errors.New("schema mismatch: " + r.Schema[0].String())
+	}
+	if len(r.Cells) > 0 {
+		return errors.New(r.Cells[0].String())
+	}
+	if len(r.LeftOnly) > 0 || len(r.RightOnly) > 0 {
+		return fmt.Errorf("unmatched rows: %v <> %v", r.LeftOnly, r.RightOnly)
+	}
+	return nil
 }
 
 func Diff(rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions) error {
+	r, _ := diffAligned(rs1, rs2, opts, true)
+	return r.Err()
+}
+
+// DiffAll compares two result sets like Diff, but reports all differences instead of the first one.
+func DiffAll(rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions) *DiffReport {
+	r, _ := diffAligned(rs1, rs2, opts, false)
+	return r
+}
+
+// DiffStat compares two result sets like DiffAll, but only counts differences without collecting them. A pair of exec
+// results is counted as a single cell, and a pair of mismatched result types is counted as an unmatched cell.
+func DiffStat(rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions) (DiffStats, error) {
+	var s DiffStats
 	if rs1.IsExecResult() != rs2.IsExecResult() {
-		return fmt.Errorf("result type mismatch: %s <> %s", rs1.String(), rs2.String())
+		s.UnmatchedCells = 1
+		return s, nil
 	}
 	if rs1.IsExecResult() {
-		if rs1.exec != rs2.exec {
-			return fmt.Errorf("execute result mismatch: %v <> %v", rs1.exec, rs2.exec)
+		s.ComparedRows, s.ComparedCells = 1, 1
+		if !opts.equalRowsAffected(rs1.exec, rs2.exec) || !opts.equalLastInsertId(rs1.exec, rs2.exec) {
+			s.MismatchedRows, s.MismatchedCells = 1, 1
+		}
+		return s, nil
+	}
+	cols, _, _ := alignColumns(rs1.cols, rs2.cols, opts)
+	keys, err := alignKeys(rs1.cols, cols, opts)
+	if err != nil {
+		return s, err
+	}
+	checkers := opts.checkers()
+	if len(keys) == 0 && !opts.Unordered {
+		// positional pairs are counted without being built
+		n := minInt(rs1.NRows(), rs2.NRows())
+		for i := 0; i < n; i++ {
+			s.countRow(rs1, rs2, indexPair{i, i}, cols, checkers)
+		}
+		s.LeftOnlyRows, s.RightOnlyRows = rs1.NRows()-n, rs2.NRows()-n
+	} else {
+		var rows []indexPair
+		var leftRows, rightRows []int
+		if len(keys) > 0 {
+			rows, leftRows, rightRows = matchRowsByKey(rs1, rs2, keys)
+		} else {
+			rows, leftRows, rightRows = matchRows(rs1, rs2, cols, checkers)
+		}
+		for _, p := range rows {
+			s.countRow(rs1, rs2, p, cols, checkers)
+		}
+		s.LeftOnlyRows, s.RightOnlyRows = len(leftRows), len(rightRows)
+	}
+	s.UnmatchedCells = (s.LeftOnlyRows + s.RightOnlyRows) * len(cols)
+	return s, nil
+}
+
+// countRow compares a pair of rows and counts the differences.
+func (s *DiffStats) countRow(rs1 *ResultSet, rs2 *ResultSet, p indexPair, cols []indexPair, checkers []ValueChecker) {
+	s.ComparedRows++
+	mismatched := false
+	for _, c := range cols {
+		s.ComparedCells++
+		v1, _ := rs1.RawValue(p.left, c.left)
+		v2, _ := rs2.RawValue(p.right, c.right)
+		if !equalValue(checkers, p.left, c.left, rs1.cols[c.left], v1, v2) {
+			mismatched = true
+			s.MismatchedCells++
+			s.countColumnMismatch(rs1.cols[c.left].Name, 1)
 		}
-		return nil
 	}
-	if rs1.NCols() != rs2.NCols() {
-		return fmt.Errorf("col count mismatch: %d <> %d", rs1.NCols(), rs2.NCols())
+	if mismatched {
+		s.MismatchedRows++
 	}
-	if rs1.NRows() != rs2.NRows() {
-		return fmt.Errorf("row count mismatch: %d <> %d", rs1.NRows(), rs2.NRows())
+}
+
+func diffAligned(rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions, failFast bool) (*DiffReport, *alignment) {
+	r := &DiffReport{
+		LeftExec:  rs1.exec,
+		RightExec: rs2.exec,
+		LeftCols:  rs1.NCols(),
+		RightCols: rs2.NCols(),
+		LeftRows:  rs1.NRows(),
+		RightRows: rs2.NRows(),
+	}
+	if rs1.IsExecResult() != rs2.IsExecResult() {
+		r.TypeMismatch = true
+		return r, nil
+	}
+	if rs1.IsExecResult() {
+		r.ExecMismatch = !opts.equalRowsAffected(rs1.exec, rs2.exec) || !opts.equalLastInsertId(rs1.exec, rs2.exec)
+		return r, nil
+	}
+	r.commonOnly = opts.CommonColumnsOnly
+	a, err := align(rs1, rs2, opts)
+	if err != nil {
+		r.invalid = err
+		return r, nil
+	}
+	r.LeftOnlyCols, r.RightOnlyCols = a.leftCols, a.rightCols
+	r.LeftOnly, r.RightOnly = a.leftRows, a.rightRows
+	r.LeftOnlyRows, r.RightOnlyRows = len(a.leftRows), len(a.rightRows)
+	r.UnmatchedCells = (r.LeftOnlyRows + r.RightOnlyRows) * len(a.cols)
+	if failFast && r.Err() != nil {
+		return r, a
 	}
 	if opts.CheckSchema {
-		schemaDiff := diffSchema(rs1.cols, rs2.cols, opts)
-		if len(schemaDiff) > 0 {
-			return fmt.Errorf("schema mismatch: " + schemaDiff)
+		r.Schema = diffSchema(rs1.cols, rs2.cols, a.cols, opts)
+		if failFast && len(r.Schema) > 0 {
+			return r, a
+		}
+	}
+
+	checkers := opts.checkers()
+	for _, p := range a.rows {
+		r.ComparedRows++
+		mismatched := false
+		for _, c := range a.cols {
+			r.ComparedCells++
+			v1, _ := rs1.RawValue(p.left, c.left)
+			v2, _ := rs2.RawValue(p.right, c.right)
+			if equalValue(checkers, p.left, c.left, rs1.cols[c.left], v1, v2) {
+				continue
+			}
+			mismatched = true
+			r.MismatchedCells++
+			r.countColumnMismatch(rs1.cols[c.left].Name, 1)
+			r.Cells = append(r.Cells, CellDiff{
+				Row:       p.left,
+				RightRow:  p.right,
+				Col:       c.left,
+				Name:      rs1.cols[c.left].Name,
+				Left:      v1,
+				Right:     v2,
+				LeftNull:  rs1.isNil(p.left, c.left),
+				RightNull: rs2.isNil(p.right, c.right),
+			})
+			if failFast {
+				return r, a
+			}
+		}
+		if mismatched {
+			r.MismatchedRows++
 		}
 	}
-	checkers := opts.ValueCheckers
-	if checkers == nil {
-		checkers = []ValueChecker{RawBytesChecker{}}
+	return r, a
+}
+
+// alignment describes how columns and rows of two result sets correspond to each other.
+type alignment struct {
+	cols      []indexPair
+	leftCols  []int
+	rightCols []int
+	rows      []indexPair
+	leftRows  []int
+	rightRows []int
+}
+
+func align(rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions) (*alignment, error) {
+	a := &alignment{}
+	a.cols, a.leftCols, a.rightCols = alignColumns(rs1.cols, rs2.cols, opts)
+	keys, err := alignKeys(rs1.cols, a.cols, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		a.rows, a.leftRows, a.rightRows = matchRowsByKey(rs1, rs2, keys)
+	} else if opts.Unordered {
+		a.rows, a.leftRows, a.rightRows = matchRows(rs1, rs2, a.cols, opts.checkers())
+	} else {
+		a.rows, a.leftRows, a.rightRows = zip(rs1.NRows(), rs2.NRows())
 	}
+	return a, nil
+}
 
-	for i := 0; i < rs1.NRows(); i++ {
-		for j := 0; j < rs1.NCols(); j++ {
-			v1, _ := rs1.RawValue(i, j)
-			v2, _ := rs2.RawValue(i, j)
-			for _, checker := range checkers {
-				if checker.Match(i, j, rs1.cols[j]) {
-					if !checker.Equal(v1, v2, rs1.cols[j]) {
-						return fmt.Errorf("data mismatch (%q#%d): %v <> %v", rs1.cols[j].Name, i, v1, v2)
-					}
-					break
-				}
+// alignColumns pairs up columns by names or by positions, ignored columns are excluded.
+func alignColumns(cols1 []ColumnDef, cols2 []ColumnDef, opts DiffOptions) ([]indexPair, []int, []int) {
+	if opts.AlignColumnsByName {
+		return matchColumns(cols1, cols2, opts)
+	}
+	pairs, leftOnly, rightOnly := zip(len(cols1), len(cols2))
+	cols := pairs[:0]
+	for _, c := range pairs {
+		if !opts.ignored(cols1[c.left].Name) {
+			cols = append(cols, c)
+		}
+	}
+	return cols, leftOnly, rightOnly
+}
+
+// alignKeys resolves key columns to a subset of the aligned column pairs.
+func alignKeys(cols1 []ColumnDef, cols []indexPair, opts DiffOptions) ([]indexPair, error) {
+	keys := make([]indexPair, 0, len(opts.KeyIndexes)+len(opts.KeyColumns))
+	for _, j := range opts.KeyIndexes {
+		k := -1
+		for i, c := range cols {
+			if c.left == j {
+				k = i
+				break
 			}
 		}
+		if k < 0 {
+			return nil, fmt.Errorf("invalid key index: %d", j)
+		}
+		keys = append(keys, cols[k])
 	}
-	return nil
+	for _, name := range opts.KeyColumns {
+		k := -1
+		for i, c := range cols {
+			if opts.sameName(cols1[c.left].Name, name) {
+				k = i
+				break
+			}
+		}
+		if k < 0 {
+			return nil, fmt.Errorf("invalid key column: %q", name)
+		}
+		keys = append(keys, cols[k])
+	}
+	return keys, nil
 }
 
-func diffSchema(cols1 []ColumnDef, cols2 []ColumnDef, opts DiffOptions) string {
-	for i := range cols1 {
-		t1, t2 := cols1[i], cols2[i]
-		if t1.Name != t2.Name {
-			return fmt.Sprintf("cols[%d].name: %s <> %s", i, t1.Name, t2.Name)
+// matchColumns pairs up columns of two result sets by names, columns sharing the same name are paired in order of
+// their occurrences.
+func matchColumns(cols1 []ColumnDef, cols2 []ColumnDef, opts DiffOptions) ([]indexPair, []int, []int) {
+	var (
+		pairs     []indexPair
+		leftOnly  []int
+		rightOnly []int
+	)
+	matched := make([]bool, len(cols2))
+	for j, c1 := range cols1 {
+		if opts.ignored(c1.Name) {
+			continue
+		}
+		found := false
+		for k, c2 := range cols2 {
+			if !matched[k] && opts.sameName(c1.Name, c2.Name) {
+				pairs = append(pairs, indexPair{j, k})
+				matched[k], found = true, true
+				break
+			}
+		}
+		if !found {
+			leftOnly = append(leftOnly, j)
+		}
+	}
+	for k, c2 := range cols2 {
+		if !matched[k] && !opts.ignored(c2.Name) {
+			rightOnly = append(rightOnly, k)
 		}
-		if t1.Type != t2.Type {
-			return fmt.Sprintf("cols[%d].type: %s <> %s", i, t1.Type, t2.Type)
+	}
+	return pairs, leftOnly, rightOnly
+}
+
+type indexPair struct {
+	left  int
+	right int
+}
+
+func zip(n1 int, n2 int) ([]indexPair, []int, []int) {
+	var (
+		pairs     []indexPair
+		leftOnly  []int
+		rightOnly []int
+	)
+	for i := 0; i < n1 || i < n2; i++ {
+		if i >= n2 {
+			leftOnly = append(leftOnly, i)
+		} else if i >= n1 {
+			rightOnly = append(rightOnly, i)
+		} else {
+			pairs = append(pairs, indexPair{i, i})
 		}
-		if t1.HasNullable != t2.HasNullable || t1.Nullable != t2.Nullable {
-			return fmt.Sprintf("cols[%d].nullable: %v <> %v", i, t1.Nullable, t2.Nullable)
+	}
+	return pairs, leftOnly, rightOnly
+}
+
+// matchRows pairs up rows of two result sets regardless of their positions. Rows with identical raw values are paired
+// first, which is all if values are compared byte by byte. Otherwise, the remaining rows are paired by the value checkers
+// via augmenting paths, which may re-pair rows paired before, so that as many rows as possible are paired even if the
+// checkers are tolerant. It takes O(pending * rows) row comparisons in the worst case, where pending is the number of
+// rows without identical counterparts.
+func matchRows(rs1 *ResultSet, rs2 *ResultSet, cols []indexPair, checkers []ValueChecker) ([]indexPair, []int, []int) {
+	var (
+		pairs     []indexPair
+		leftOnly  []int
+		rightOnly []int
+	)
+	equalRows := func(i int, k int) bool {
+		for _, c := range cols {
+			v1, _ := rs1.RawValue(i, c.left)
+			v2, _ := rs2.RawValue(k, c.right)
+			if !equalValue(checkers, i, c.left, rs1.cols[c.left], v1, v2) {
+				return false
+			}
 		}
+		return true
+	}
 
-		if t1.HasLength != t2.HasLength || t1.Length != t2.Length {
-			return fmt.Sprintf("cols[%d].type: %s(%d) <> %s(%d)", i, t1.Type, t1.Length, t2.Type, t2.Length)
+	buckets := make(map[string][]int)
+	for k := 0; k < rs2.NRows(); k++ {
+		key := rs2.rowKey(k, rights(cols))
+		buckets[key] = append(buckets[key], k)
+	}
+	// owners[k] is the left row paired with the k-th right row, or -1.
+	owners := make([]int, rs2.NRows())
+	for k := range owners {
+		owners[k] = -1
+	}
+	var pending []int
+	for i := 0; i < rs1.NRows(); i++ {
+		key := rs1.rowKey(i, lefts(cols))
+		if ks := buckets[key]; len(ks) > 0 && equalRows(i, ks[0]) {
+			owners[ks[0]] = i
+			buckets[key] = ks[1:]
+			continue
 		}
-		if opts.CheckPrecision {
-			if t1.HasPrecisionScale != t2.HasPrecisionScale || t1.Precision != t2.Precision || t1.Scale != t2.Scale {
-				return fmt.Sprintf("cols[%d].type: %s(%d,%d) <> %s(%d,%d)", i,
-					t1.Type, t1.Precision, t1.Scale, t2.Type, t2.Precision, t2.Scale)
+		pending = append(pending, i)
+	}
+	// visited[k] == round marks the k-th right row as visited in the current search.
+	visited, round := make([]int, len(owners)), 0
+	var augment func(i int) bool
+	augment = func(i int) bool {
+		for k, owner := range owners {
+			if visited[k] == round || !equalRows(i, k) {
+				continue
 			}
+			visited[k] = round
+			if owner < 0 || augment(owner) {
+				owners[k] = i
+				return true
+			}
+		}
+		return false
+	}
+	exact := len(checkers) > 0
+	for _, checker := range checkers {
+		if _, ok := checker.(RawBytesChecker); !ok {
+			exact = false
+		}
+	}
+	for _, i := range pending {
+		if exact {
+			leftOnly = append(leftOnly, i)
+			continue
+		}
+		round++
+		if !augment(i) {
+			leftOnly = append(leftOnly, i)
 		}
 	}
-	return ""
+	for k, i := range owners {
+		if i < 0 {
+			rightOnly = append(rightOnly, k)
+		} else {
+			pairs = append(pairs, indexPair{i, k})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a].left < pairs[b].left })
+	return pairs, leftOnly, rightOnly
+}
+
+// matchRowsByKey pairs up rows of two result sets by the raw values of key columns, rows sharing the same key are paired
+// in order of their occurrences.
+func matchRowsByKey(rs1 *ResultSet, rs2 *ResultSet, keys []indexPair) ([]indexPair, []int, []int) {
+	var (
+		pairs     []indexPair
+		leftOnly  []int
+		rightOnly []int
+	)
+	index := make(map[string][]int)
+	for k := 0; k < rs2.NRows(); k++ {
+		key := rs2.rowKey(k, rights(keys))
+		index[key] = append(index[key], k)
+	}
+	matched := make([]bool, rs2.NRows())
+	for i := 0; i < rs1.NRows(); i++ {
+		key := rs1.rowKey(i, lefts(keys))
+		if ks := index[key]; len(ks) > 0 {
+			pairs = append(pairs, indexPair{i, ks[0]})
+			matched[ks[0]] = true
+			index[key] = ks[1:]
+		} else {
+			leftOnly = append(leftOnly, i)
+		}
+	}
+	for k := range matched {
+		if !matched[k] {
+			rightOnly = append(rightOnly, k)
+		}
+	}
+	return pairs, leftOnly, rightOnly
+}
+
+func equalValue(checkers []ValueChecker, row int, col int, def ColumnDef, v1 []byte, v2 []byte) bool {
+	for _, checker := range checkers {
+		if checker.Match(row, col, def) {
+			return checker.Equal(v1, v2, def)
+		}
+	}
+	return true
+}
+
+func diffSchema(cols1 []ColumnDef, cols2 []ColumnDef, cols []indexPair, opts DiffOptions) []SchemaDiff {
+	var diffs []SchemaDiff
+	for _, c := range cols {
+		diffs = append(diffs, diffColumnDef(c.left, cols1[c.left], cols2[c.right], opts)...)
+	}
+	return diffs
+}
+
+func diffColumnDef(i int, t1 ColumnDef, t2 ColumnDef, opts DiffOptions) []SchemaDiff {
+	var diffs []SchemaDiff
+	if !opts.sameName(t1.Name, t2.Name) {
+		diffs = append(diffs, SchemaDiff{i, "name", t1.Name, t2.Name})
+	}
+	if t1.Type != t2.Type {
+		diffs = append(diffs, SchemaDiff{i, "type", t1.Type, t2.Type})
+	}
+	if t1.HasNullable != t2.HasNullable || t1.Nullable != t2.Nullable {
+		diffs = append(diffs, SchemaDiff{i, "nullable", strconv.FormatBool(t1.Nullable), strconv.FormatBool(t2.Nullable)})
+	}
+	if t1.HasLength != t2.HasLength || t1.Length != t2.Length {
+		diffs = append(diffs, SchemaDiff{i, "type",
+			fmt.Sprintf("%s(%d)", t1.Type, t1.Length), fmt.Sprintf("%s(%d)", t2.Type, t2.Length)})
+	}
+	if opts.CheckPrecision {
+		if t1.HasPrecisionScale != t2.HasPrecisionScale || t1.Precision != t2.Precision || t1.Scale != t2.Scale {
+			diffs = append(diffs, SchemaDiff{i, "type",
+				fmt.Sprintf("%s(%d,%d)", t1.Type, t1.Precision, t1.Scale), fmt.Sprintf("%s(%d,%d)", t2.Type, t2.Precision, t2.Scale)})
+		}
+	}
+	return diffs
+}
+
+func describeResult(ncols int, nrows int, exec ExecResult) string {
+	if ncols == 0 {
+		return strconv.FormatInt(exec.RowsAffected, 10) + " rows affected"
+	}
+	if nrows == 0 {
+		return "empty set"
+	}
+	return strconv.Itoa(nrows) + " rows in set"
+}
+
+func lefts(pairs []indexPair) []int {
+	xs := make([]int, len(pairs))
+	for i, p := range pairs {
+		xs[i] = p.left
+	}
+	return xs
+}
+
+func rights(pairs []indexPair) []int {
+	xs := make([]int, len(pairs))
+	for i, p := range pairs {
+		xs[i] = p.right
+	}
+	return xs
 }
@@ -0,0 +1,332 @@
+package sqlz
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResultSet(cols []ColumnDef, rows ...[]interface{}) *ResultSet {
+	rs := New(cols)
+	for i, row := range rows {
+		cells := rs.AllocateRow()
+		for j, v := range row {
+			switch x := v.(type) {
+			case nil:
+				rs.markNil(i, j)
+			case string:
+				*cells[j].(*[]byte) = []byte(x)
+			case []byte:
+				*cells[j].(*[]byte) = x
+			}
+		}
+	}
+	return rs
+}
+
+func TestDiffAll(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"3", nil},
+	)
+	rs2 := newTestResultSet([]ColumnDef{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "VARCHAR"}},
+		[]interface{}{"1", "x"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"4", "c"},
+		[]interface{}{"5", "d"},
+	)
+
+	r := DiffAll(rs1, rs2, DiffOptions{CheckSchema: true})
+	require.False(t, r.Equal())
+	require.EqualError(t, r.Err(), "row count mismatch: 3 <> 4")
+	require.Equal(t, []SchemaDiff{{0, "type", "INT", "BIGINT"}}, r.Schema)
+	require.Equal(t, 3, r.ComparedRows)
+	require.Equal(t, 6, r.ComparedCells)
+	require.Equal(t, 2, r.MismatchedRows)
+	require.Equal(t, 3, r.MismatchedCells)
+	require.Equal(t, []int{3}, r.RightOnly)
+	require.Equal(t, []CellDiff{
+		{Row: 0, RightRow: 0, Col: 1, Name: "name", Left: []byte("a"), Right: []byte("x")},
+		{Row: 2, RightRow: 2, Col: 0, Name: "id", Left: []byte("3"), Right: []byte("4")},
+		{Row: 2, RightRow: 2, Col: 1, Name: "name", Left: nil, Right: []byte("c"), LeftNull: true},
+	}, r.Cells)
+
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{}), "row count mismatch: 3 <> 4")
+	require.NoError(t, Diff(rs1, rs1, DiffOptions{}))
+	require.EqualError(t, Diff(rs1, newTestResultSet(cols, []interface{}{"1", "a"}, []interface{}{"2", "c"}, []interface{}{"3", nil}), DiffOptions{}),
+		`data mismatch ("name"#1): [98] <> [99]`)
+
+	pct := []ColumnDef{{Name: "pct%d", Type: "INT"}}
+	require.EqualError(t, Diff(newTestResultSet(pct, []interface{}{"1"}), newTestResultSet(pct, []interface{}{"2"}), DiffOptions{}),
+		`data mismatch ("pct%d"#0): [49] <> [50]`)
+	require.EqualError(t, Diff(newTestResultSet(pct), New([]ColumnDef{{Name: "pct%d", Type: "BIGINT"}}), DiffOptions{CheckSchema: true}),
+		`schema mismatch: cols[0].type: INT <> BIGINT`)
+}
+
+func TestDiffAllEqual(t *testing.T) {
+	for i, rs := range rss {
+		r := DiffAll(&rs, &rs, DiffOptions{CheckSchema: true, CheckPrecision: true})
+		require.True(t, r.Equal(), "#%d", i)
+		require.Empty(t, r.Cells, "#%d", i)
+	}
+	r := DiffAll(&rss[0], &rss[2], DiffOptions{})
+	require.True(t, r.TypeMismatch)
+	require.EqualError(t, r.Err(), "result type mismatch: 0 rows affected <> empty set")
+}
+
+type floatChecker struct{}
+
+func (c floatChecker) Match(row int, col int, def ColumnDef) bool { return def.Type == "FLOAT" }
+
+func (c floatChecker) Equal(v1 []byte, v2 []byte, def ColumnDef) bool {
+	return Float(mustParseFloat(string(v1)), 0.01).EqualTo(def, v2)
+}
+
+func mustParseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestDiffUnordered(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "val", Type: "FLOAT"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "1.00"},
+		[]interface{}{"2", "2.00"},
+		[]interface{}{"2", "2.00"},
+		[]interface{}{"3", "3.00"},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"2", "2.00"},
+		[]interface{}{"4", "4.00"},
+		[]interface{}{"1", "1.001"},
+		[]interface{}{"2", "2.00"},
+	)
+
+	require.Error(t, Diff(rs1, rs2, DiffOptions{}))
+	r := DiffAll(rs1, rs2, DiffOptions{Unordered: true})
+	require.EqualError(t, r.Err(), "unmatched rows: [0 3] <> [1 2]")
+
+	checkers := []ValueChecker{floatChecker{}, RawBytesChecker{}}
+	r = DiffAll(rs1, rs2, DiffOptions{Unordered: true, ValueCheckers: checkers})
+	require.EqualError(t, r.Err(), "unmatched rows: [3] <> [1]")
+	require.Equal(t, 3, r.ComparedRows)
+	require.Zero(t, r.MismatchedCells)
+
+	rs2 = newTestResultSet(cols,
+		[]interface{}{"3", "3.00"},
+		[]interface{}{"2", "2.00"},
+		[]interface{}{"1", "1.001"},
+		[]interface{}{"2", "2.00"},
+	)
+	require.NoError(t, Diff(rs1, rs2, DiffOptions{Unordered: true, ValueCheckers: checkers}))
+	require.Error(t, Diff(rs1, rs2, DiffOptions{Unordered: true}))
+
+	// pairing the identical 1.01 first would leave 1.00 and 1.02 unpaired
+	floats := []ColumnDef{{Name: "val", Type: "DOUBLE"}}
+	rs1 = newTestResultSet(floats, []interface{}{"1.00"}, []interface{}{"1.01"})
+	rs2 = newTestResultSet(floats, []interface{}{"1.01"}, []interface{}{"1.02"})
+	r = DiffAll(rs1, rs2, DiffOptions{Unordered: true, ValueCheckers: []ValueChecker{FloatChecker{AbsTol: 0.011}}})
+	require.NoError(t, r.Err())
+	require.Equal(t, 2, r.ComparedRows)
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{Unordered: true}), "unmatched rows: [0] <> [1]")
+
+	// raw bytes pairing doesn't search for augmenting paths
+	rs1, rs2 = New(floats), New(floats)
+	for i := 0; i < 20000; i++ {
+		rs1.appendRaw([][]byte{[]byte(strconv.Itoa(i))})
+		rs2.appendRaw([][]byte{[]byte(strconv.Itoa(i + i%2*20000))})
+	}
+	stats, err := DiffStat(rs1, rs2, DiffOptions{Unordered: true})
+	require.NoError(t, err)
+	require.Equal(t, 10000, stats.ComparedRows)
+	require.Equal(t, 10000, stats.LeftOnlyRows)
+	require.Equal(t, 10000, stats.RightOnlyRows)
+}
+
+func TestDiffByKey(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "k", Type: "INT"}, {Name: "val", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "1", "a"},
+		[]interface{}{"2", "1", "b"},
+		[]interface{}{"3", "1", "c"},
+		[]interface{}{"1", "2", nil},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"4", "1", "d"},
+		[]interface{}{"1", "2", "a"},
+		[]interface{}{"3", "1", "c"},
+		[]interface{}{"1", "1", "x"},
+	)
+
+	for _, opts := range []DiffOptions{
+		{KeyColumns: []string{"id", "k"}},
+		{KeyIndexes: []int{0, 1}},
+		{KeyColumns: []string{"k"}, KeyIndexes: []int{0}},
+	} {
+		r := DiffAll(rs1, rs2, opts)
+		require.Equal(t, []int{1}, r.LeftOnly)
+		require.Equal(t, []int{0}, r.RightOnly)
+		require.Equal(t, 3, r.ComparedRows)
+		require.Equal(t, 2, r.MismatchedRows)
+		require.Equal(t, []CellDiff{
+			{Row: 0, RightRow: 3, Col: 2, Name: "val", Left: []byte("a"), Right: []byte("x")},
+			{Row: 3, RightRow: 1, Col: 2, Name: "val", Left: nil, Right: []byte("a"), LeftNull: true},
+		}, r.Cells)
+	}
+
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{KeyColumns: []string{"foo"}}), `invalid key column: "foo"`)
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{KeyIndexes: []int{3}}), "invalid key index: 3")
+}
+
+func TestDiffAlignColumnsByName(t *testing.T) {
+	rs1 := newTestResultSet([]ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}, {Name: "ts", Type: "DATETIME"}},
+		[]interface{}{"1", "a", "2021-01-01 00:00:00"},
+		[]interface{}{"2", "b", "2021-01-02 00:00:00"},
+	)
+	rs2 := newTestResultSet([]ColumnDef{{Name: "NAME", Type: "VARCHAR"}, {Name: "extra", Type: "INT"}, {Name: "ID", Type: "INT"}, {Name: "ts", Type: "DATETIME"}},
+		[]interface{}{"a", "0", "1", "2021-01-01 00:00:01"},
+		[]interface{}{"x", "0", "2", "2021-01-02 00:00:01"},
+	)
+
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{}), "col count mismatch: 3 <> 4")
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{AlignColumnsByName: true}), "col count mismatch: 3 <> 4")
+
+	r := DiffAll(rs1, rs2, DiffOptions{AlignColumnsByName: true, IgnoreColumnCase: true})
+	require.Equal(t, []int{1}, r.RightOnlyCols)
+	require.Empty(t, r.LeftOnlyCols)
+
+	opts := DiffOptions{AlignColumnsByName: true, IgnoreColumnCase: true, CommonColumnsOnly: true, CheckSchema: true}
+	r = DiffAll(rs1, rs2, opts)
+	require.Empty(t, r.Schema)
+	require.Equal(t, 6, r.ComparedCells)
+	require.Equal(t, 3, r.MismatchedCells)
+	require.Equal(t, CellDiff{Row: 1, RightRow: 1, Col: 1, Name: "name", Left: []byte("b"), Right: []byte("x")}, r.Cells[1])
+
+	opts.IgnoreColumns = []string{"TS"}
+	require.EqualError(t, Diff(rs1, rs2, opts), `data mismatch ("name"#1): [98] <> [120]`)
+	opts.KeyColumns = []string{"Id"}
+	r = DiffAll(rs1, rs2, opts)
+	require.Equal(t, 4, r.ComparedCells)
+	require.Equal(t, 1, r.MismatchedCells)
+	opts.KeyColumns = []string{"ts"}
+	require.EqualError(t, Diff(rs1, rs2, opts), `invalid key column: "ts"`)
+
+	rs2 = newTestResultSet([]ColumnDef{{Name: "ID", Type: "INT"}, {Name: "NAME", Type: "VARCHAR"}},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+	)
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{}), "col count mismatch: 3 <> 2")
+	require.NoError(t, Diff(rs1, rs2, DiffOptions{CommonColumnsOnly: true}))
+	require.NoError(t, Diff(rs1, rs2, DiffOptions{CommonColumnsOnly: true, CheckSchema: true, IgnoreColumnCase: true}))
+	require.EqualError(t, Diff(rs1, rs2, DiffOptions{CommonColumnsOnly: true, CheckSchema: true}), "schema mismatch: cols[0].name: id <> ID")
+}
+
+func TestDiffExecResult(t *testing.T) {
+	for i, tt := range []struct {
+		e1   ExecResult
+		e2   ExecResult
+		opts DiffOptions
+		ok   bool
+	}{
+		{ExecResult{1, 10, true, true}, ExecResult{1, 10, true, true}, DiffOptions{}, true},
+		{ExecResult{1, 10, true, true}, ExecResult{1, 12, true, true}, DiffOptions{}, false},
+		{ExecResult{1, 10, true, true}, ExecResult{1, 12, true, true}, DiffOptions{IgnoreLastInsertId: true}, true},
+		{ExecResult{1, 10, true, true}, ExecResult{1, 12, true, true}, DiffOptions{LastInsertIdDelta: 2}, true},
+		{ExecResult{1, 10, true, true}, ExecResult{1, 12, true, true}, DiffOptions{LastInsertIdDelta: 1}, false},
+		{ExecResult{1, 10, true, true}, ExecResult{2, 10, true, true}, DiffOptions{IgnoreLastInsertId: true}, false},
+		{ExecResult{1, 10, true, true}, ExecResult{0, 0, false, false}, DiffOptions{}, false},
+		{ExecResult{1, 10, true, true}, ExecResult{0, 0, false, false}, DiffOptions{UnsupportedAsWildcard: true}, true},
+		{ExecResult{1, 10, true, true}, ExecResult{2, 0, true, false}, DiffOptions{UnsupportedAsWildcard: true}, false},
+		{ExecResult{1, 10, true, false}, ExecResult{1, 0, true, true}, DiffOptions{LastInsertIdDelta: 10}, false},
+	} {
+		err := Diff(&ResultSet{exec: tt.e1}, &ResultSet{exec: tt.e2}, tt.opts)
+		if tt.ok {
+			require.NoError(t, err, "#%d", i)
+		} else {
+			require.Error(t, err, "#%d", i)
+			require.Contains(t, err.Error(), "execute result mismatch", "#%d", i)
+		}
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"3", nil},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"1", "x"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"4", "c"},
+		[]interface{}{"5", "d"},
+	)
+
+	s, err := DiffStat(rs1, rs2, DiffOptions{})
+	require.NoError(t, err)
+	require.Equal(t, DiffStats{
+		ComparedRows:     3,
+		ComparedCells:    6,
+		MismatchedRows:   2,
+		MismatchedCells:  3,
+		RightOnlyRows:    1,
+		UnmatchedCells:   2,
+		ColumnMismatches: map[string]int{"id": 1, "name": 2},
+	}, s)
+	require.Equal(t, s, DiffAll(rs1, rs2, DiffOptions{}).DiffStats)
+	require.InDelta(t, 3.0/8, s.Similarity(), 1e-9)
+
+	s, err = DiffStat(rs1, rs2, DiffOptions{KeyIndexes: []int{0}})
+	require.NoError(t, err)
+	require.Equal(t, DiffStats{
+		ComparedRows:     2,
+		ComparedCells:    4,
+		MismatchedRows:   1,
+		MismatchedCells:  1,
+		LeftOnlyRows:     1,
+		RightOnlyRows:    2,
+		UnmatchedCells:   6,
+		ColumnMismatches: map[string]int{"name": 1},
+	}, s)
+	require.InDelta(t, 0.3, s.Similarity(), 1e-9)
+	require.Equal(t, s, DiffAll(rs1, rs2, DiffOptions{KeyIndexes: []int{0}}).DiffStats)
+	unordered, err := DiffStat(rs1, rs2, DiffOptions{Unordered: true})
+	require.NoError(t, err)
+	require.Equal(t, unordered, DiffAll(rs1, rs2, DiffOptions{Unordered: true}).DiffStats)
+	_, err = DiffStat(rs1, rs2, DiffOptions{KeyColumns: []string{"x"}})
+	require.EqualError(t, err, `invalid key column: "x"`)
+
+	total := DiffStats{}
+	for i := range rss {
+		for k := range rss {
+			s, err := DiffStat(&rss[i], &rss[k], DiffOptions{})
+			require.NoError(t, err)
+			if i == k {
+				require.Equal(t, 1.0, s.Similarity())
+			}
+			total.Add(s)
+		}
+	}
+	require.Equal(t, DiffStats{
+		ComparedRows:    4 + 3,
+		ComparedCells:   4 + 3,
+		MismatchedRows:  2,
+		MismatchedCells: 2,
+		LeftOnlyRows:    3,
+		RightOnlyRows:   3,
+		UnmatchedCells:  8 + 6,
+	}, total)
+	total.Add(s)
+	require.Equal(t, 11, total.ComparedCells)
+	require.Equal(t, map[string]int{"name": 1}, total.ColumnMismatches)
+
+	_, err = DiffStat(rs1, rs2, DiffOptions{KeyIndexes: []int{2}})
+	require.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package sqlz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type RowsDiffOptions struct {
+	DiffOptions
+	// MaxDiffs limits the number of collected cell diffs and unmatched rows of each side, it's 1000 by default and a
+	// negative value means no limit. All differences are counted in DiffStats anyway.
+	MaxDiffs int
+	// CompareKey compares values of a key column in the sorted-merge mode. By default, NULL comes first, numbers are
+	// compared numerically and others are compared bytewise.
+	CompareKey func(def ColumnDef, v1 []byte, v2 []byte) int
+}
+
+func (opts RowsDiffOptions) maxDiffs() int {
+	if opts.MaxDiffs == 0 {
+		return 1000
+	}
+	return opts.MaxDiffs
+}
+
+// DiffRows compares two row iterators like DiffAll, but walks them in lockstep and keeps only the current rows in
+// memory. Rows are paired by positions, or merged by key columns if KeyColumns or KeyIndexes is specified, in which case
+// both sides must be sorted by the keys in ascending order. Unordered is not supported. The returned error reports
+// failures of reading rows, while differences are reported by the DiffReport.
+func DiffRows(rows1 RowIterator, rows2 RowIterator, opts RowsDiffOptions) (*DiffReport, error) {
+	defer rows1.Close()
+	defer rows2.Close()
+	cols1, err := readColumnDefs(rows1)
+	if err != nil {
+		return nil, err
+	}
+	cols2, err := readColumnDefs(rows2)
+	if err != nil {
+		return nil, err
+	}
+	r := &DiffReport{LeftCols: len(cols1), RightCols: len(cols2), commonOnly: opts.CommonColumnsOnly}
+	cols, leftCols, rightCols := alignColumns(cols1, cols2, opts.DiffOptions)
+	r.LeftOnlyCols, r.RightOnlyCols = leftCols, rightCols
+	keys, err := alignKeys(cols1, cols, opts.DiffOptions)
+	if err != nil {
+		r.invalid = err
+		return r, nil
+	}
+	if opts.Unordered && len(keys) == 0 {
+		r.invalid = errors.New("unordered diff is not supported on row iterators")
+		return r, nil
+	}
+	if opts.CheckSchema {
+		r.Schema = diffSchema(cols1, cols2, cols, opts.DiffOptions)
+	}
+	compareKey := opts.CompareKey
+	if compareKey == nil {
+		compareKey = defaultCompareKey
+	}
+	compareKeys := func(rs1 *ResultSet, rs2 *ResultSet, keys []indexPair) int {
+		for _, c := range keys {
+			v1, _ := rs1.RawValue(0, c.left)
+			v2, _ := rs2.RawValue(0, c.right)
+			if x := compareKey(cols1[c.left], v1, v2); x != 0 {
+				return x
+			}
+		}
+		return 0
+	}
+	maxDiffs := opts.maxDiffs()
+	collectable := func(n int) bool { return maxDiffs < 0 || n < maxDiffs }
+
+	c1 := &rowCursor{rows: rows1, cur: New(cols1), keys: lefts(keys), compare: compareKey, side: "left", n: -1, ok: true}
+	c2 := &rowCursor{rows: rows2, cur: New(cols2), keys: rights(keys), compare: compareKey, side: "right", n: -1, ok: true}
+	if err = c1.next(); err != nil {
+		return r, err
+	}
+	if err = c2.next(); err != nil {
+		return r, err
+	}
+
+	checkers := opts.checkers()
+	for c1.ok || c2.ok {
+		cmp := 0
+		if !c1.ok {
+			cmp = 1
+		} else if !c2.ok {
+			cmp = -1
+		} else if len(keys) > 0 {
+			cmp = compareKeys(c1.cur, c2.cur, keys)
+		}
+		if cmp < 0 {
+			r.LeftOnlyRows++
+			if collectable(len(r.LeftOnly)) {
+				r.LeftOnly = append(r.LeftOnly, c1.n)
+			}
+			if err = c1.next(); err != nil {
+				return r, err
+			}
+			continue
+		}
+		if cmp > 0 {
+			r.RightOnlyRows++
+			if collectable(len(r.RightOnly)) {
+				r.RightOnly = append(r.RightOnly, c2.n)
+			}
+			if err = c2.next(); err != nil {
+				return r, err
+			}
+			continue
+		}
+
+		r.ComparedRows++
+		mismatched := false
+		for _, c := range cols {
+			r.ComparedCells++
+			v1, _ := c1.cur.RawValue(0, c.left)
+			v2, _ := c2.cur.RawValue(0, c.right)
+			if equalValue(checkers, c1.n, c.left, cols1[c.left], v1, v2) {
+				continue
+			}
+			mismatched = true
+			r.MismatchedCells++
+			r.countColumnMismatch(cols1[c.left].Name, 1)
+			if collectable(len(r.Cells)) {
+				r.Cells = append(r.Cells, CellDiff{
+					Row:       c1.n,
+					RightRow:  c2.n,
+					Col:       c.left,
+					Name:      cols1[c.left].Name,
+					Left:      v1,
+					Right:     v2,
+					LeftNull:  c1.cur.isNil(0, c.left),
+					RightNull: c2.cur.isNil(0, c.right),
+				})
+			}
+		}
+		if mismatched {
+			r.MismatchedRows++
+		}
+		if err = c1.next(); err != nil {
+			return r, err
+		}
+		if err = c2.next(); err != nil {
+			return r, err
+		}
+	}
+	r.LeftRows, r.RightRows = c1.n+1, c2.n+1
+	r.UnmatchedCells = (r.LeftOnlyRows + r.RightOnlyRows) * len(cols)
+	return r, nil
+}
+
+// rowCursor reads rows one by one into a single-row result set, and verifies the order of rows if key columns are
+// given.
+type rowCursor struct {
+	rows    RowIterator
+	cur     *ResultSet
+	keys    []int
+	compare func(def ColumnDef, v1 []byte, v2 []byte) int
+	side    string
+	prev    [][]byte
+	n       int
+	ok      bool
+}
+
+func (c *rowCursor) next() error {
+	if !c.ok {
+		return nil
+	}
+	if c.n >= 0 && len(c.keys) > 0 {
+		// scanned values are never reused, so it's safe to keep the previous key without copying.
+		c.prev = c.prev[:0]
+		for _, j := range c.keys {
+			v, _ := c.cur.RawValue(0, j)
+			c.prev = append(c.prev, v)
+		}
+	}
+	c.cur.reset()
+	if !c.rows.Next() {
+		c.ok = false
+		return c.rows.Err()
+	}
+	if err := c.cur.scanRow(c.rows); err != nil {
+		c.ok = false
+		return err
+	}
+	c.n++
+	if c.n == 0 {
+		return nil
+	}
+	for k, j := range c.keys {
+		v, _ := c.cur.RawValue(0, j)
+		if x := c.compare(c.cur.cols[j], c.prev[k], v); x < 0 {
+			return nil
+		} else if x > 0 {
+			return fmt.Errorf("%s rows are not sorted by keys: row#%d", c.side, c.n)
+		}
+	}
+	return nil
+}
+
+func defaultCompareKey(def ColumnDef, v1 []byte, v2 []byte) int {
+	if v1 == nil || v2 == nil {
+		switch {
+		case v1 == nil && v2 == nil:
+			return 0
+		case v1 == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if matchType(def, "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR",
+		"DECIMAL", "NUMERIC", "FLOAT", "DOUBLE", "REAL") {
+		x1, ok1 := new(big.Rat).SetString(string(v1))
+		x2, ok2 := new(big.Rat).SetString(string(v2))
+		if ok1 && ok2 {
+			return x1.Cmp(x2)
+		}
+	}
+	return bytes.Compare(v1, v2)
+}
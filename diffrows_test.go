@@ -0,0 +1,126 @@
+package sqlz
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRows(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "v", Type: "DOUBLE"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "1.0"},
+		[]interface{}{"2", "2.0"},
+		[]interface{}{"3", nil},
+	)
+	rs2 := newTestResultSet([]ColumnDef{{Name: "id", Type: "BIGINT"}, {Name: "v", Type: "DOUBLE"}},
+		[]interface{}{"1", "1.0000001"},
+		[]interface{}{"2", "2.5"},
+		[]interface{}{"3", "3.0"},
+		[]interface{}{"4", "4.0"},
+	)
+
+	r, err := DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{DiffOptions: DiffOptions{
+		CheckSchema:   true,
+		ValueCheckers: []ValueChecker{FloatChecker{AbsTol: 1e-6}},
+	}})
+	require.NoError(t, err)
+	require.EqualError(t, r.Err(), "row count mismatch: 3 <> 4")
+	require.Equal(t, []SchemaDiff{{0, "type", "INT", "BIGINT"}}, r.Schema)
+	require.Equal(t, 3, r.ComparedRows)
+	require.Equal(t, 6, r.ComparedCells)
+	require.Equal(t, 2, r.MismatchedRows)
+	require.Equal(t, 2, r.MismatchedCells)
+	require.Equal(t, []int{3}, r.RightOnly)
+	require.Equal(t, 2, r.UnmatchedCells)
+	require.Equal(t, []CellDiff{
+		{Row: 1, RightRow: 1, Col: 1, Name: "v", Left: []byte("2.0"), Right: []byte("2.5")},
+		{Row: 2, RightRow: 2, Col: 1, Name: "v", Left: nil, Right: []byte("3.0"), LeftNull: true},
+	}, r.Cells)
+
+	r, err = DiffRows(rs1.Rows(), rs1.Rows(), RowsDiffOptions{})
+	require.NoError(t, err)
+	require.True(t, r.Equal())
+	require.Equal(t, 3, r.LeftRows)
+	require.Equal(t, 3, r.RightRows)
+
+	r, err = DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{MaxDiffs: 1})
+	require.NoError(t, err)
+	require.Equal(t, 3, r.MismatchedCells)
+	require.Len(t, r.Cells, 1)
+
+	left, right := New(cols), New(cols)
+	for i := 0; i < 3000; i++ {
+		left.appendRaw([][]byte{[]byte(strconv.Itoa(2 * i)), []byte("1")})
+		right.appendRaw([][]byte{[]byte(strconv.Itoa(2*i + 1)), []byte("2")})
+	}
+	r, err = DiffRows(left.Rows(), right.Rows(), RowsDiffOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.Cells, 1000)
+	require.Equal(t, 6000, r.MismatchedCells)
+	require.Equal(t, 3000, r.MismatchedRows)
+	r, err = DiffRows(left.Rows(), right.Rows(), RowsDiffOptions{DiffOptions: DiffOptions{KeyColumns: []string{"id"}}})
+	require.NoError(t, err)
+	require.Len(t, r.LeftOnly, 1000)
+	require.Len(t, r.RightOnly, 1000)
+	require.Equal(t, 3000, r.LeftOnlyRows)
+	require.Equal(t, 3000, r.RightOnlyRows)
+	require.Equal(t, 12000, r.UnmatchedCells)
+	r, err = DiffRows(left.Rows(), right.Rows(), RowsDiffOptions{MaxDiffs: -1})
+	require.NoError(t, err)
+	require.Len(t, r.Cells, 6000)
+
+	r, err = DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{DiffOptions: DiffOptions{Unordered: true}})
+	require.NoError(t, err)
+	require.EqualError(t, r.Err(), "unordered diff is not supported on row iterators")
+}
+
+func TestDiffRowsByKey(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{nil, "z"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"9", "c"},
+		[]interface{}{"10", "d"},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "x"},
+		[]interface{}{"10", "d"},
+		[]interface{}{"11", "e"},
+	)
+
+	r, err := DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{DiffOptions: DiffOptions{KeyColumns: []string{"id"}}})
+	require.NoError(t, err)
+	require.Equal(t, 2, r.ComparedRows)
+	require.Equal(t, 1, r.MismatchedRows)
+	require.Equal(t, []int{0, 2}, r.LeftOnly)
+	require.Equal(t, []int{0, 3}, r.RightOnly)
+	require.Equal(t, []CellDiff{{Row: 1, RightRow: 1, Col: 1, Name: "name", Left: []byte("b"), Right: []byte("x")}}, r.Cells)
+	require.Equal(t, map[string]int{"name": 1}, r.ColumnMismatches)
+	require.Equal(t, 8, r.UnmatchedCells)
+
+	stats := DiffAll(rs1, rs2, DiffOptions{KeyColumns: []string{"id"}}).DiffStats
+	require.Equal(t, stats, r.DiffStats)
+
+	r, err = DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{
+		DiffOptions: DiffOptions{KeyIndexes: []int{1}},
+		MaxDiffs:    1,
+	})
+	require.EqualError(t, err, "right rows are not sorted by keys: row#2")
+	require.Equal(t, 2, r.RightOnlyRows)
+	require.Equal(t, []int{0}, r.RightOnly)
+
+	r, err = DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{
+		DiffOptions: DiffOptions{KeyIndexes: []int{0}},
+		CompareKey: func(def ColumnDef, v1 []byte, v2 []byte) int {
+			return len(v2) - len(v1)
+		},
+	})
+	require.EqualError(t, err, "right rows are not sorted by keys: row#2")
+
+	r, err = DiffRows(rs1.Rows(), rs2.Rows(), RowsDiffOptions{DiffOptions: DiffOptions{KeyColumns: []string{"x"}}})
+	require.NoError(t, err)
+	require.EqualError(t, r.Err(), `invalid key column: "x"`)
+}
@@ -0,0 +1,186 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// ResultRows iterates over the rows of a result set or a Decoder without any database connection, it implements
+// RowIterator. Values are read in place, so the result set must not be modified during the iteration. ResultRows holds
+// no resources, Close only ends the iteration, and it's closed automatically after the last row.
+type ResultRows struct {
+	cols   []ColumnDef
+	next   func() (*ResultSet, int, bool)
+	err    func() error
+	cur    *ResultSet
+	pos    int
+	closed bool
+}
+
+var errRowsClosed = errors.New("rows are closed")
+
+// Rows replays the result set as ResultRows. Column types are derived from ColumnDef, thus ReadFromRows(rs.Rows())
+// reproduces the result set.
+func (rs *ResultSet) Rows() *ResultRows {
+	i := -1
+	return &ResultRows{cols: rs.cols, next: func() (*ResultSet, int, bool) {
+		i++
+		return rs, i, i < rs.NRows()
+	}}
+}
+
+// ColumnDefs returns the column definitions, which are preferred to ColumnTypes by functions consuming a RowIterator.
+func (r *ResultRows) ColumnDefs() []ColumnDef { return r.cols }
+
+func (r *ResultRows) Columns() ([]string, error) {
+	if r.closed {
+		return nil, errRowsClosed
+	}
+	return columnDefs(r.cols).Columns(), nil
+}
+
+// ColumnTypes derives column types from the column definitions, it's done by a throwaway *sql.Rows without any row
+// because sql.ColumnType can only be built by database/sql.
+func (r *ResultRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	if r.closed {
+		return nil, errRowsClosed
+	}
+	rows, err := openRows(&driverRows{columnDefs: columnDefs(r.cols), rs: &ResultSet{}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.ColumnTypes()
+}
+
+func (r *ResultRows) Next() bool {
+	if r.closed {
+		return false
+	}
+	var ok bool
+	if r.cur, r.pos, ok = r.next(); !ok {
+		r.Close()
+	}
+	return ok
+}
+
+// Scan copies the values of the current row into dest, see ResultSet.ScanRow for supported types of dest.
+func (r *ResultRows) Scan(dest ...interface{}) error {
+	if r.closed {
+		return errRowsClosed
+	}
+	if r.cur == nil {
+		return errors.New("scan called without calling next")
+	}
+	return r.cur.ScanRow(r.pos, dest...)
+}
+
+func (r *ResultRows) Err() error {
+	if r.err == nil {
+		return nil
+	}
+	return r.err()
+}
+
+func (r *ResultRows) Close() error {
+	r.closed, r.cur = true, nil
+	return nil
+}
+
+// sqlRows replays the result set as *sql.Rows for interfaces which require it, see openRows.
+func (rs *ResultSet) sqlRows() (*sql.Rows, error) {
+	return openRows(&driverRows{columnDefs: columnDefs(rs.cols), rs: rs})
+}
+
+// openRows wraps driver rows as *sql.Rows by a connection which answers any query with the rows. The DB is closed
+// right after the query, which only prevents new queries: the connection stays open until the returned rows are
+// closed, either explicitly or after the last row.
+func openRows(rows driver.Rows) (*sql.Rows, error) {
+	db := sql.OpenDB(rowsConnector{rows})
+	defer db.Close()
+	return db.Query("")
+}
+
+type rowsConnector struct{ rows driver.Rows }
+
+func (c rowsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return rowsConn{c.rows}, nil
+}
+
+func (c rowsConnector) Driver() driver.Driver { return rowsDriver{} }
+
+type rowsDriver struct{}
+
+func (d rowsDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("open is not supported")
+}
+
+type rowsConn struct{ rows driver.Rows }
+
+func (c rowsConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c rowsConn) Close() error { return nil }
+
+func (c rowsConn) Begin() (driver.Tx, error) { return nil, errors.New("begin is not supported") }
+
+func (c rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.rows, nil
+}
+
+// columnDefs implements the column type methods of driver.Rows.
+type columnDefs []ColumnDef
+
+func (cs columnDefs) Columns() []string {
+	names := make([]string, len(cs))
+	for i, c := range cs {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (cs columnDefs) ColumnTypeDatabaseTypeName(index int) string { return cs[index].Type }
+
+func (cs columnDefs) ColumnTypeLength(index int) (int64, bool) {
+	return cs[index].Length, cs[index].HasLength
+}
+
+func (cs columnDefs) ColumnTypeNullable(index int) (bool, bool) {
+	return cs[index].Nullable, cs[index].HasNullable
+}
+
+func (cs columnDefs) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	return cs[index].Precision, cs[index].Scale, cs[index].HasPrecisionScale
+}
+
+func (cs columnDefs) ColumnTypeScanType(index int) reflect.Type {
+	return reflect.TypeOf(sql.RawBytes{})
+}
+
+type driverRows struct {
+	columnDefs
+	rs  *ResultSet
+	pos int
+}
+
+func (r *driverRows) Close() error { return nil }
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if r.pos >= r.rs.NRows() {
+		return io.EOF
+	}
+	for j := range dest {
+		if r.rs.isNil(r.pos, j) {
+			dest[j] = nil
+		} else {
+			dest[j], _ = r.rs.RawValue(r.pos, j)
+		}
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,64 @@
+package sqlz
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRows(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, Nullable: true, HasNullable: true, HasLength: true},
+		{Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true},
+	}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "foo", "1.50"},
+		[]interface{}{"2", "", nil},
+		[]interface{}{"3", nil, "-0.01"},
+	)
+
+	rows := rs1.Rows()
+	names, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "price"}, names)
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Len(t, types, 3)
+	require.Equal(t, "DECIMAL", types[2].DatabaseTypeName())
+	precision, scale, ok := types[2].DecimalSize()
+	require.True(t, ok)
+	require.Equal(t, []int64{10, 2}, []int64{precision, scale})
+	require.EqualError(t, rows.Scan(new(int), new(string), new(string)), "scan called without calling next")
+	var (
+		id    int
+		name  sql.NullString
+		price *float64
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &name, &price))
+	require.Equal(t, 1, id)
+	require.Equal(t, "foo", name.String)
+	require.Equal(t, 1.5, *price)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &name, &price))
+	require.True(t, name.Valid)
+	require.Nil(t, price)
+	require.True(t, rows.Next())
+	require.False(t, rows.Next())
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.EqualError(t, rows.Scan(&id, &name, &price), "rows are closed")
+	_, err = rows.Columns()
+	require.EqualError(t, err, "rows are closed")
+
+	for _, rs := range []*ResultSet{rs1, &rss[2], &rss[3]} {
+		rs2, err := ReadFromRows(rs.Rows())
+		require.NoError(t, err)
+		require.NoError(t, Diff(rs, rs2, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+		require.Equal(t, rs.cols, rs2.cols)
+		require.Equal(t, rs.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+}
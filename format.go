@@ -0,0 +1,257 @@
+package sqlz
+
+import (
+	"bufio"
+	"encoding/hex"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RawTableFormatter is an optional interface of TableFormatter. Dump appends raw values to it instead of strings, where
+// NULL is nil, so that NULL can be told from the string 'NULL'.
+type RawTableFormatter interface {
+	TableFormatter
+	AppendRaw(row [][]byte)
+}
+
+type TableStyle int
+
+const (
+	// StyleBox is the ASCII table of the mysql client.
+	StyleBox TableStyle = iota
+	// StyleVertical is the vertical format of the mysql client (\G).
+	StyleVertical
+	// StyleTSV is the tab-separated format of the mysql client in batch mode, special characters are escaped.
+	StyleTSV
+	// StyleCSV is the RFC 4180 format, values equal to the NULL marker are quoted to be told from NULL.
+	StyleCSV
+	StyleMarkdown
+	StyleHTML
+)
+
+type FormatOptions struct {
+	Style TableStyle
+	// MaxWidth truncates values longer than it, zero means no limit.
+	MaxWidth int
+	// Hex renders non-printable values as hex literals like 0x0102.
+	Hex bool
+	// Null is the text of NULL values, "NULL" by default.
+	Null string
+}
+
+// TableWriter is a TableFormatter writing tables to an io.Writer in the given style. Rows are buffered to align columns,
+// so Flush must be called after all rows are appended.
+type TableWriter struct {
+	w    io.Writer
+	opts FormatOptions
+	hdr  []string
+	rows [][]tableCell
+}
+
+type tableCell struct {
+	s    string
+	null bool
+}
+
+func NewTableWriter(w io.Writer, opts FormatOptions) *TableWriter {
+	if len(opts.Null) == 0 {
+		opts.Null = "NULL"
+	}
+	return &TableWriter{w: w, opts: opts}
+}
+
+func (t *TableWriter) SetHeader(hdr []string) { t.hdr = hdr }
+
+func (t *TableWriter) Append(row []string) {
+	cells := make([]tableCell, len(row))
+	for j, s := range row {
+		cells[j] = tableCell{s: truncateCell(s, t.opts.MaxWidth)}
+	}
+	t.rows = append(t.rows, cells)
+}
+
+func (t *TableWriter) AppendRaw(row [][]byte) {
+	cells := make([]tableCell, len(row))
+	for j, v := range row {
+		if v == nil {
+			cells[j] = tableCell{s: t.opts.Null, null: true}
+		} else if t.opts.Hex && !isPrintable(v) {
+			cells[j] = tableCell{s: truncateCell("0x"+hex.EncodeToString(v), t.opts.MaxWidth)}
+		} else {
+			cells[j] = tableCell{s: truncateCell(string(v), t.opts.MaxWidth)}
+		}
+	}
+	t.rows = append(t.rows, cells)
+}
+
+// Flush writes the table and resets appended rows.
+func (t *TableWriter) Flush() error {
+	bw := bufio.NewWriter(t.w)
+	switch t.opts.Style {
+	case StyleVertical:
+		t.writeVertical(bw)
+	case StyleTSV:
+		t.writeTSV(bw)
+	case StyleCSV:
+		t.writeCSV(bw)
+	case StyleMarkdown:
+		t.writeMarkdown(bw)
+	case StyleHTML:
+		t.writeHTML(bw)
+	default:
+		t.writeBox(bw)
+	}
+	t.rows = nil
+	return bw.Flush()
+}
+
+func (t *TableWriter) widths(f func(string) string) []int {
+	widths := make([]int, len(t.hdr))
+	for j, h := range t.hdr {
+		widths[j] = utf8.RuneCountInString(f(h))
+	}
+	for _, row := range t.rows {
+		for j, c := range row {
+			if j < len(widths) {
+				widths[j] = maxInt(widths[j], utf8.RuneCountInString(f(c.s)))
+			}
+		}
+	}
+	return widths
+}
+
+func (t *TableWriter) writeBox(w *bufio.Writer) {
+	widths := t.widths(func(s string) string { return s })
+	border := "+"
+	for _, n := range widths {
+		border += strings.Repeat("-", n+2) + "+"
+	}
+	line := func(cells []string) {
+		w.WriteString("|")
+		for j, s := range cells {
+			w.WriteString(" " + s + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s)) + " |")
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString(border + "\n")
+	line(t.hdr)
+	w.WriteString(border + "\n")
+	for _, row := range t.rows {
+		line(mapCells(row, func(c tableCell) string { return c.s }))
+	}
+	if len(t.rows) > 0 {
+		w.WriteString(border + "\n")
+	}
+}
+
+func (t *TableWriter) writeVertical(w *bufio.Writer) {
+	width := 0
+	for _, h := range t.hdr {
+		width = maxInt(width, utf8.RuneCountInString(h))
+	}
+	stars := strings.Repeat("*", 27)
+	for i, row := range t.rows {
+		w.WriteString(stars + " " + strconv.Itoa(i+1) + ". row " + stars + "\n")
+		for j, c := range row {
+			if j < len(t.hdr) {
+				w.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(t.hdr[j])) + t.hdr[j] + ": " + c.s + "\n")
+			}
+		}
+	}
+}
+
+func (t *TableWriter) writeTSV(w *bufio.Writer) {
+	escape := strings.NewReplacer("\\", `\\`, "\x00", `\0`, "\t", `\t`, "\n", `\n`).Replace
+	w.WriteString(strings.Join(mapStrings(t.hdr, escape), "\t") + "\n")
+	for _, row := range t.rows {
+		w.WriteString(strings.Join(mapCells(row, func(c tableCell) string {
+			if c.null {
+				return c.s
+			}
+			return escape(c.s)
+		}), "\t") + "\n")
+	}
+}
+
+func (t *TableWriter) writeCSV(w *bufio.Writer) {
+	w.WriteString(strings.Join(mapStrings(t.hdr, func(s string) string { return quoteCSV(s, t.opts.Null, ',') }), ",") + "\r\n")
+	for _, row := range t.rows {
+		w.WriteString(strings.Join(mapCells(row, func(c tableCell) string {
+			if c.null {
+				return c.s
+			}
+			return quoteCSV(c.s, t.opts.Null, ',')
+		}), ",") + "\r\n")
+	}
+}
+
+func (t *TableWriter) writeMarkdown(w *bufio.Writer) {
+	escape := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace
+	widths := t.widths(escape)
+	for j := range widths {
+		widths[j] = maxInt(widths[j], 3)
+	}
+	line := func(cells []string) {
+		w.WriteString("|")
+		for j, s := range cells {
+			w.WriteString(" " + s + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s)) + " |")
+		}
+		w.WriteString("\n")
+	}
+	line(mapStrings(t.hdr, escape))
+	seps := make([]string, len(widths))
+	for j, n := range widths {
+		seps[j] = strings.Repeat("-", n)
+	}
+	line(seps)
+	for _, row := range t.rows {
+		line(mapCells(row, func(c tableCell) string { return escape(c.s) }))
+	}
+}
+
+func (t *TableWriter) writeHTML(w *bufio.Writer) {
+	w.WriteString("<table>\n<thead>\n<tr>")
+	for _, h := range t.hdr {
+		w.WriteString("<th>" + html.EscapeString(h) + "</th>")
+	}
+	w.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range t.rows {
+		w.WriteString("<tr>")
+		for _, c := range row {
+			if c.null {
+				w.WriteString(`<td class="null">` + html.EscapeString(c.s) + "</td>")
+			} else {
+				w.WriteString("<td>" + html.EscapeString(c.s) + "</td>")
+			}
+		}
+		w.WriteString("</tr>\n")
+	}
+	w.WriteString("</tbody>\n</table>\n")
+}
+
+// quoteCSV quotes s by RFC 4180 if it's required, or if it's equal to the NULL marker.
+func quoteCSV(s string, null string, comma rune) string {
+	if s == null || len(s) > 0 && (s[0] == ' ' || s[len(s)-1] == ' ') || strings.ContainsAny(s, "\"\r\n"+string(comma)) {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
+
+func mapStrings(xs []string, f func(string) string) []string {
+	out := make([]string, len(xs))
+	for j, s := range xs {
+		out[j] = f(s)
+	}
+	return out
+}
+
+func mapCells(cells []tableCell, f func(tableCell) string) []string {
+	out := make([]string, len(cells))
+	for j, c := range cells {
+		out[j] = f(c)
+	}
+	return out
+}
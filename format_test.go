@@ -0,0 +1,118 @@
+package sqlz
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableWriter(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}, {Name: "data", Type: "BLOB"}}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", nil, []byte{0, 1}},
+		[]interface{}{"2", "NULL", "a|b"},
+		[]interface{}{"3", "", "x,\"y\"\n"},
+		[]interface{}{"4", "<b>very long</b>", "t\\t"},
+	)
+	for _, tt := range []struct {
+		opts   FormatOptions
+		expect string
+	}{
+		{FormatOptions{Hex: true, MaxWidth: 10}, `
++----+------------+------------+
+| id | name       | data       |
++----+------------+------------+
+| 1  | NULL       | 0x0001     |
+| 2  | NULL       | a|b        |
+| 3  |            | 0x782c2... |
+| 4  | <b>very... | t\t        |
++----+------------+------------+
+`},
+		{FormatOptions{Style: StyleVertical, MaxWidth: 10}, `
+*************************** 1. row ***************************
+  id: 1
+name: NULL
+data: ` + "\x00\x01" + `
+*************************** 2. row ***************************
+  id: 2
+name: NULL
+data: a|b
+*************************** 3. row ***************************
+  id: 3
+name: 
+data: x,"y"
+` + `
+*************************** 4. row ***************************
+  id: 4
+name: <b>very...
+data: t\t
+`},
+		{FormatOptions{Style: StyleTSV}, `
+id	name	data
+1	NULL	\0` + "\x01" + `
+2	NULL	a|b
+3		x,"y"\n
+4	<b>very long</b>	t\\t
+`},
+		{FormatOptions{Style: StyleCSV, Hex: true}, "\n" +
+			"id,name,data\r\n" +
+			"1,NULL,0x0001\r\n" +
+			"2,\"NULL\",a|b\r\n" +
+			"3,,0x782c2279220a\r\n" +
+			"4,<b>very long</b>,t\\t\r\n"},
+		{FormatOptions{Style: StyleCSV, Null: `\N`}, "\n" +
+			"id,name,data\r\n" +
+			"1,\\N,\x00\x01\r\n" +
+			"2,NULL,a|b\r\n" +
+			"3,,\"x,\"\"y\"\"\n\"\r\n" +
+			"4,<b>very long</b>,t\\t\r\n"},
+		{FormatOptions{Style: StyleMarkdown, Hex: true}, `
+| id  | name             | data           |
+| --- | ---------------- | -------------- |
+| 1   | NULL             | 0x0001         |
+| 2   | NULL             | a\|b           |
+| 3   |                  | 0x782c2279220a |
+| 4   | <b>very long</b> | t\t            |
+`},
+		{FormatOptions{Style: StyleHTML}, `
+<table>
+<thead>
+<tr><th>id</th><th>name</th><th>data</th></tr>
+</thead>
+<tbody>
+<tr><td>1</td><td class="null">NULL</td><td>` + "\x00\x01" + `</td></tr>
+<tr><td>2</td><td>NULL</td><td>a|b</td></tr>
+<tr><td>3</td><td></td><td>x,&#34;y&#34;
+</td></tr>
+<tr><td>4</td><td>&lt;b&gt;very long&lt;/b&gt;</td><td>t\t</td></tr>
+</tbody>
+</table>
+`},
+	} {
+		var buf bytes.Buffer
+		w := NewTableWriter(&buf, tt.opts)
+		rs.Dump(w)
+		require.NoError(t, w.Flush())
+		require.Equal(t, tt.expect[1:], buf.String())
+	}
+
+	var buf bytes.Buffer
+	w := NewTableWriter(&buf, FormatOptions{Style: StyleTSV})
+	rs.Dump(w)
+	require.NoError(t, w.Flush())
+	rows, err := ParseRows(buf.String())
+	require.NoError(t, err)
+	require.Equal(t, Rows{
+		{"1", nil, "\x00\x01"},
+		{"2", nil, "a|b"},
+		{"3", "", "x,\"y\"\n"},
+		{"4", "<b>very long</b>", "t\\t"},
+	}, rows)
+
+	buf.Reset()
+	w = NewTableWriter(&buf, FormatOptions{Style: StyleCSV})
+	NewFromResult(execResult{ExecResult{RowsAffected: 1, HasRowsAffected: true}}).Dump(w)
+	require.NoError(t, w.Flush())
+	require.Equal(t, "RowsAffected,LastInsertId\r\n1,NULL\r\n", buf.String())
+}
@@ -0,0 +1,240 @@
+package sqlz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UpdateGolden makes AssertGolden (re)write golden files instead of comparing with them. Test packages usually bind it
+// to their own flag, e.g. flag.BoolVar(&sqlz.UpdateGolden, "update", false, "update golden files").
+var UpdateGolden bool
+
+// GoldenT is the subset of testing.TB used by AssertGolden.
+type GoldenT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// AssertGolden compares rs with the golden file testdata/<name>.golden by Diff, and reports a rendered diff on failure.
+// Golden files are (re)written instead if UpdateGolden is set. CheckSchema is enabled unless opts is given.
+func AssertGolden(t GoldenT, name string, rs *ResultSet, opts ...DiffOptions) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if UpdateGolden {
+		var buf bytes.Buffer
+		if err := writeGolden(&buf, rs); err != nil {
+			t.Fatalf("encode golden %s: %v", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("update golden %s: %v", path, err)
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("update golden %s: %v", path, err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("read golden %s: %v (set UpdateGolden to create it)", path, err)
+	}
+	defer f.Close()
+	exp, err := readGolden(f)
+	if err != nil {
+		t.Fatalf("read golden %s: %v", path, err)
+	}
+	opt := DiffOptions{CheckSchema: true}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if err = Diff(exp, rs, opt); err != nil {
+		var buf bytes.Buffer
+		RenderDiff(&buf, exp, rs, opt, RenderOptions{})
+		t.Errorf("result set mismatches golden %s: %v\n%s", path, err, buf.String())
+	}
+}
+
+// writeGolden writes rs in a line-oriented text format, cells are separated by tabs and formatted by formatGoldenValue.
+func writeGolden(w io.Writer, rs *ResultSet) error {
+	bw := bufio.NewWriter(w)
+	if rs.IsExecResult() {
+		format := func(x int64, ok bool) string {
+			if !ok {
+				return "NULL"
+			}
+			return strconv.FormatInt(x, 10)
+		}
+		fmt.Fprintf(bw, "-- exec\nrows_affected\t%s\nlast_insert_id\t%s\n",
+			format(rs.exec.RowsAffected, rs.exec.HasRowsAffected), format(rs.exec.LastInsertId, rs.exec.HasLastInsertId))
+		return bw.Flush()
+	}
+	bw.WriteString("-- columns\n")
+	for _, c := range rs.cols {
+		fields := []string{formatGoldenValue([]byte(c.Name), false), formatGoldenValue([]byte(c.Type), false)}
+		if c.HasLength {
+			fields = append(fields, "length="+strconv.FormatInt(c.Length, 10))
+		}
+		if c.HasPrecisionScale {
+			fields = append(fields, fmt.Sprintf("precision=%d,%d", c.Precision, c.Scale))
+		}
+		if c.HasNullable {
+			fields = append(fields, "nullable="+strconv.FormatBool(c.Nullable))
+		}
+		bw.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+	bw.WriteString("-- rows\n")
+	row := make([]string, rs.NCols())
+	for i := 0; i < rs.NRows(); i++ {
+		for j := range row {
+			v, _ := rs.RawValue(i, j)
+			row[j] = formatGoldenValue(v, rs.isNil(i, j))
+		}
+		bw.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	return bw.Flush()
+}
+
+func readGolden(r io.Reader) (*ResultSet, error) {
+	rs := &ResultSet{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	section, n := "", 0
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		if strings.HasPrefix(line, "-- ") {
+			section = line[3:]
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		var err error
+		switch section {
+		case "exec":
+			err = parseGoldenExec(&rs.exec, fields)
+		case "columns":
+			var c ColumnDef
+			if c, err = parseGoldenColumn(fields); err == nil {
+				rs.cols = append(rs.cols, c)
+			}
+		case "rows":
+			err = rs.appendGoldenRow(fields)
+		default:
+			err = fmt.Errorf("unexpected section: %q", section)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid golden line#%d: %w", n, err)
+		}
+	}
+	return rs, sc.Err()
+}
+
+func parseGoldenExec(e *ExecResult, fields []string) error {
+	if len(fields) != 2 {
+		return fmt.Errorf("expected 2 fields, not %d", len(fields))
+	}
+	var x *int64
+	var ok *bool
+	switch fields[0] {
+	case "rows_affected":
+		x, ok = &e.RowsAffected, &e.HasRowsAffected
+	case "last_insert_id":
+		x, ok = &e.LastInsertId, &e.HasLastInsertId
+	default:
+		return fmt.Errorf("unknown exec field: %q", fields[0])
+	}
+	if fields[1] == "NULL" {
+		return nil
+	}
+	v, err := strconv.ParseInt(fields[1], 10, 64)
+	*x, *ok = v, err == nil
+	return err
+}
+
+func parseGoldenColumn(fields []string) (ColumnDef, error) {
+	var c ColumnDef
+	if len(fields) < 2 {
+		return c, fmt.Errorf("expected at least 2 fields, not %d", len(fields))
+	}
+	name, isNil, err := parseGoldenValue(fields[0])
+	if err != nil || isNil {
+		return c, fmt.Errorf("invalid column name: %q", fields[0])
+	}
+	typ, isNil, err := parseGoldenValue(fields[1])
+	if err != nil || isNil {
+		return c, fmt.Errorf("invalid column type: %q", fields[1])
+	}
+	c.Name, c.Type = string(name), string(typ)
+	for _, attr := range fields[2:] {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 {
+			return c, fmt.Errorf("invalid column attribute: %q", attr)
+		}
+		switch kv[0] {
+		case "length":
+			c.Length, err = strconv.ParseInt(kv[1], 10, 64)
+			c.HasLength = true
+		case "precision":
+			_, err = fmt.Sscanf(kv[1], "%d,%d", &c.Precision, &c.Scale)
+			c.HasPrecisionScale = true
+		case "nullable":
+			c.Nullable, err = strconv.ParseBool(kv[1])
+			c.HasNullable = true
+		default:
+			err = fmt.Errorf("unknown column attribute: %q", attr)
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+func (rs *ResultSet) appendGoldenRow(fields []string) error {
+	if len(fields) != rs.NCols() {
+		return fmt.Errorf("expected %d fields, not %d", rs.NCols(), len(fields))
+	}
+	row := make([][]byte, len(fields))
+	for j, s := range fields {
+		v, isNil, err := parseGoldenValue(s)
+		if err != nil {
+			return err
+		}
+		if !isNil {
+			row[j] = append([]byte{}, v...)
+		}
+	}
+	rs.appendRaw(row)
+	return nil
+}
+
+// formatGoldenValue is like formatValue, but also quotes printable values with the hex prefix or the section prefix so
+// that it's reversible.
+func formatGoldenValue(v []byte, isNil bool) string {
+	s := formatValue(v, isNil)
+	if !isNil && isPrintable(v) && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "-- ")) {
+		return "'" + s + "'"
+	}
+	return s
+}
+
+func parseGoldenValue(s string) ([]byte, bool, error) {
+	switch {
+	case s == "NULL":
+		return nil, true, nil
+	case len(s) >= 2 && strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'"):
+		return []byte(strings.ReplaceAll(s[1:len(s)-1], "''", "'")), false, nil
+	case strings.HasPrefix(s, "0x"):
+		v, err := hex.DecodeString(s[2:])
+		return v, false, err
+	}
+	return []byte(s), false, nil
+}
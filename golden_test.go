@@ -0,0 +1,82 @@
+package sqlz
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	flag.BoolVar(&UpdateGolden, "update", false, "update golden files")
+}
+
+type goldenT struct {
+	*testing.T
+	errs []string
+}
+
+func (t *goldenT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func (t *goldenT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func TestGolden(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, HasLength: true},
+		{Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true},
+	}
+	rows := [][]interface{}{
+		{"1", "foo", "1.50"},
+		{"2", nil, "0.00"},
+		{"3", "", nil},
+		{"4", "NULL", "2.00"},
+		{"5", "0x1f", "3.00"},
+		{"6", []byte{0, 1, '\t'}, "4.00"},
+		{"7", "it's", "5.00"},
+	}
+	rs := newTestResultSet(cols, rows...)
+	sections := newTestResultSet([]ColumnDef{{Name: "-- rows", Type: "TEXT"}}, []interface{}{"-- rows"}, []interface{}{"-- columns"})
+	for _, tt := range []*ResultSet{rs, sections, New(cols), NewFromResult(execResult{ExecResult{RowsAffected: 3, HasRowsAffected: true}})} {
+		var buf bytes.Buffer
+		require.NoError(t, writeGolden(&buf, tt))
+		rs2, err := readGolden(&buf)
+		require.NoError(t, err)
+		require.NoError(t, Diff(tt, rs2, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+		require.Equal(t, tt.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+
+	AssertGolden(t, "golden", rs)
+	AssertGolden(t, "golden-exec", NewFromResult(execResult{ExecResult{1, 42, true, true}}))
+
+	if UpdateGolden {
+		return
+	}
+	gt := &goldenT{T: t}
+	rows[0][1] = "bar"
+	AssertGolden(gt, "golden", newTestResultSet(cols, rows...))
+	require.Len(t, gt.errs, 1)
+	require.Contains(t, gt.errs[0], `data mismatch ("name"#0)`)
+	require.Contains(t, gt.errs[0], "- 1  | *foo*    | 1.50\n+ 1  | *bar*    | 1.50\n")
+	AssertGolden(gt, "golden", rs, DiffOptions{ValueCheckers: []ValueChecker{IgnoreChecker{}}})
+	require.Len(t, gt.errs, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		AssertGolden(gt, "missing", rs)
+	}()
+	<-done
+	require.Len(t, gt.errs, 2)
+	require.Contains(t, gt.errs[1], "UpdateGolden")
+
+	_, err := readGolden(bytes.NewBufferString("-- columns\nid\tINT\n-- rows\n1\t2\n"))
+	require.EqualError(t, err, "invalid golden line#4: expected 1 fields, not 2")
+}
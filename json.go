@@ -0,0 +1,273 @@
+package sqlz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+type jsonColumn struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Length    *int64 `json:"length,omitempty"`
+	Precision *int64 `json:"precision,omitempty"`
+	Scale     *int64 `json:"scale,omitempty"`
+	Nullable  *bool  `json:"nullable,omitempty"`
+}
+
+type jsonExec struct {
+	RowsAffected *int64 `json:"rows_affected"`
+	LastInsertId *int64 `json:"last_insert_id"`
+}
+
+// jsonValue is encoded as null, a string, or {"base64": "..."} for values which are not valid UTF-8.
+type jsonValue []byte
+
+type jsonResultSet struct {
+	Columns []jsonColumn  `json:"columns,omitempty"`
+	Rows    [][]jsonValue `json:"rows,omitempty"`
+	Exec    *jsonExec     `json:"exec,omitempty"`
+}
+
+// MarshalJSON encodes the result set as {"columns": [...], "rows": [[...], ...]} or {"exec": {...}} for exec results.
+// NULL values are null, and values which are not valid UTF-8 are encoded as {"base64": "..."}.
+func (rs *ResultSet) MarshalJSON() ([]byte, error) {
+	if rs.IsExecResult() {
+		return json.Marshal(jsonResultSet{Exec: newJSONExec(rs.exec)})
+	}
+	tmp := jsonResultSet{Columns: newJSONColumns(rs.cols), Rows: make([][]jsonValue, rs.NRows())}
+	for i := range tmp.Rows {
+		tmp.Rows[i] = rs.jsonRow(i)
+	}
+	return json.Marshal(tmp)
+}
+
+func (rs *ResultSet) UnmarshalJSON(data []byte) error {
+	var tmp jsonResultSet
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	*rs = ResultSet{}
+	if tmp.Exec != nil {
+		rs.exec = tmp.Exec.execResult()
+		return nil
+	}
+	rs.cols = jsonColumnDefs(tmp.Columns)
+	for _, row := range tmp.Rows {
+		if err := rs.appendJSONRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSONL writes the result set in JSON Lines, see the package function WriteJSONL.
+func (rs *ResultSet) WriteJSONL(w io.Writer) error {
+	if rs.IsExecResult() {
+		return writeJSONLine(w, jsonResultSet{Exec: newJSONExec(rs.exec)})
+	}
+	return WriteJSONL(w, rs.Rows())
+}
+
+// WriteJSONL streams rows to w in JSON Lines, the first line is {"columns": [...]} and each of the following lines is a
+// row encoded as a JSON array like MarshalJSON.
+func WriteJSONL(w io.Writer, rows RowIterator) error {
+	defer rows.Close()
+	cols, err := readColumnDefs(rows)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err = writeJSONLine(bw, jsonResultSet{Columns: newJSONColumns(cols)}); err != nil {
+		return err
+	}
+	raws := make([][]byte, len(cols))
+	dest := make([]interface{}, len(cols))
+	for j := range raws {
+		dest[j] = &raws[j]
+	}
+	row := make([]jsonValue, len(cols))
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		for j, raw := range raws {
+			row[j] = jsonValue(raw)
+		}
+		if err = writeJSONLine(bw, row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadJSONL reads a result set written by WriteJSONL.
+func ReadJSONL(r io.Reader) (*ResultSet, error) {
+	dec := json.NewDecoder(r)
+	var hdr jsonResultSet
+	if err := dec.Decode(&hdr); err != nil {
+		return nil, err
+	}
+	if hdr.Exec != nil {
+		return &ResultSet{exec: hdr.Exec.execResult()}, nil
+	}
+	if len(hdr.Columns) == 0 {
+		return nil, errors.New("missing columns")
+	}
+	rs := New(jsonColumnDefs(hdr.Columns))
+	for n := 2; ; n++ {
+		var row []jsonValue
+		if err := dec.Decode(&row); err == io.EOF {
+			return rs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid line#%d: %w", n, err)
+		}
+		if err := rs.appendJSONRow(row); err != nil {
+			return nil, fmt.Errorf("invalid line#%d: %w", n, err)
+		}
+	}
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+func (rs *ResultSet) jsonRow(i int) []jsonValue {
+	row := make([]jsonValue, rs.NCols())
+	for j, v := range rs.rawRow(i) {
+		row[j] = v
+	}
+	return row
+}
+
+func (rs *ResultSet) appendJSONRow(row []jsonValue) error {
+	if len(row) != rs.NCols() {
+		return fmt.Errorf("expected %d values, not %d", rs.NCols(), len(row))
+	}
+	i, cells := rs.NRows(), rs.AllocateRow()
+	for j, v := range row {
+		if v == nil {
+			rs.markNil(i, j)
+		} else {
+			*cells[j].(*[]byte) = v
+		}
+	}
+	rs.flush()
+	return nil
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	if utf8.Valid(v) {
+		return json.Marshal(string(v))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(v)})
+}
+
+func (v *jsonValue) UnmarshalJSON(data []byte) error {
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*v = nil
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = append(jsonValue{}, s...)
+		return nil
+	}
+	var obj struct {
+		Base64 *string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj.Base64 == nil {
+		return fmt.Errorf("invalid value: %s", data)
+	}
+	raw, err := base64.StdEncoding.DecodeString(*obj.Base64)
+	if err != nil {
+		return err
+	}
+	*v = jsonValue(raw)
+	return nil
+}
+
+func newJSONColumns(cols []ColumnDef) []jsonColumn {
+	out := make([]jsonColumn, len(cols))
+	for j, c := range cols {
+		c := c
+		out[j] = jsonColumn{Name: c.Name, Type: c.Type}
+		if c.HasLength {
+			out[j].Length = &c.Length
+		}
+		if c.HasPrecisionScale {
+			out[j].Precision, out[j].Scale = &c.Precision, &c.Scale
+		}
+		if c.HasNullable {
+			out[j].Nullable = &c.Nullable
+		}
+	}
+	return out
+}
+
+func jsonColumnDefs(cols []jsonColumn) []ColumnDef {
+	out := make([]ColumnDef, len(cols))
+	for j, c := range cols {
+		out[j] = ColumnDef{Name: c.Name, Type: c.Type}
+		if c.Length != nil {
+			out[j].Length, out[j].HasLength = *c.Length, true
+		}
+		if c.Precision != nil || c.Scale != nil {
+			out[j].HasPrecisionScale = true
+			if c.Precision != nil {
+				out[j].Precision = *c.Precision
+			}
+			if c.Scale != nil {
+				out[j].Scale = *c.Scale
+			}
+		}
+		if c.Nullable != nil {
+			out[j].Nullable, out[j].HasNullable = *c.Nullable, true
+		}
+	}
+	return out
+}
+
+func newJSONExec(e ExecResult) *jsonExec {
+	out := &jsonExec{}
+	if e.HasRowsAffected {
+		out.RowsAffected = &e.RowsAffected
+	}
+	if e.HasLastInsertId {
+		out.LastInsertId = &e.LastInsertId
+	}
+	return out
+}
+
+func (e *jsonExec) execResult() ExecResult {
+	var out ExecResult
+	if e.RowsAffected != nil {
+		out.RowsAffected, out.HasRowsAffected = *e.RowsAffected, true
+	}
+	if e.LastInsertId != nil {
+		out.LastInsertId, out.HasLastInsertId = *e.LastInsertId, true
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package sqlz
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, HasLength: true},
+		{Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true},
+		{Name: "data", Type: "BLOB"},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", "foo", "1.50", []byte{0xff, 0}},
+		[]interface{}{"2", nil, "0.00", ""},
+		[]interface{}{"3", "", nil, "x"},
+	)
+	exec := NewFromResult(execResult{ExecResult{RowsAffected: 2, HasRowsAffected: true}})
+
+	raw, err := json.Marshal(rs)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"columns": [
+			{"name": "id", "type": "BIGINT", "nullable": false},
+			{"name": "name", "type": "VARCHAR", "length": 64},
+			{"name": "price", "type": "DECIMAL", "precision": 10, "scale": 2},
+			{"name": "data", "type": "BLOB"}
+		],
+		"rows": [
+			["1", "foo", "1.50", {"base64": "/wA="}],
+			["2", null, "0.00", ""],
+			["3", "", null, "x"]
+		]
+	}`, string(raw))
+	raw, err = json.Marshal(exec)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"exec": {"rows_affected": 2, "last_insert_id": null}}`, string(raw))
+
+	for _, tt := range []*ResultSet{rs, New(cols), exec} {
+		raw, err := json.Marshal(tt)
+		require.NoError(t, err)
+		rs2 := &ResultSet{}
+		require.NoError(t, json.Unmarshal(raw, rs2))
+		require.Equal(t, tt.cols, rs2.cols)
+		require.Equal(t, tt.exec, rs2.exec)
+		require.NoError(t, Diff(tt, rs2, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+		require.Equal(t, tt.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+
+		var buf bytes.Buffer
+		require.NoError(t, tt.WriteJSONL(&buf))
+		rs2, err = ReadJSONL(&buf)
+		require.NoError(t, err)
+		require.Equal(t, tt.cols, rs2.cols)
+		require.Equal(t, tt.exec, rs2.exec)
+		require.Equal(t, tt.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, rs.WriteJSONL(&buf))
+	require.Equal(t, `{"columns":[{"name":"id","type":"BIGINT","nullable":false},{"name":"name","type":"VARCHAR","length":64},{"name":"price","type":"DECIMAL","precision":10,"scale":2},{"name":"data","type":"BLOB"}]}
+["1","foo","1.50",{"base64":"/wA="}]
+["2",null,"0.00",""]
+["3","",null,"x"]
+`, buf.String())
+
+	_, err = ReadJSONL(bytes.NewBufferString(`{"columns":[{"name":"id","type":"INT"}]}` + "\n[\"1\",\"2\"]\n"))
+	require.EqualError(t, err, "invalid line#2: expected 1 values, not 2")
+	require.Error(t, json.Unmarshal([]byte(`{"columns":[{"name":"id"}],"rows":[[{"hex":"00"}]]}`), &ResultSet{}))
+}
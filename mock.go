@@ -0,0 +1,285 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MockDriverName is the name of the database/sql driver serving queries by mocks.
+const MockDriverName = "sqlz-mock"
+
+var mocks = struct {
+	sync.Mutex
+	seq uint64
+	m   map[string]*Mock
+}{m: map[string]*Mock{}}
+
+func init() { sql.Register(MockDriverName, mockDriver{}) }
+
+// Mock answers queries with the configured expectations, it can be opened by sql.Open(MockDriverName, mock.DSN()).
+type Mock struct {
+	dsn     string
+	mu      sync.Mutex
+	expects []*Expectation
+}
+
+func NewMock() *Mock {
+	mocks.Lock()
+	defer mocks.Unlock()
+	mocks.seq++
+	m := &Mock{dsn: "mock-" + strconv.FormatUint(mocks.seq, 10)}
+	mocks.m[m.dsn] = m
+	return m
+}
+
+func (m *Mock) DSN() string { return m.dsn }
+
+// DB opens a *sql.DB backed by the mock.
+func (m *Mock) DB() *sql.DB {
+	db, _ := sql.Open(MockDriverName, m.dsn)
+	return db
+}
+
+// Close unregisters the mock, then the DSN can not be opened anymore.
+func (m *Mock) Close() {
+	mocks.Lock()
+	defer mocks.Unlock()
+	delete(mocks.m, m.dsn)
+}
+
+// ExpectQuery adds an expectation matching the exact query text, it applies to both queries and execs.
+func (m *Mock) ExpectQuery(query string) *Expectation {
+	return m.expect(&Expectation{query: query})
+}
+
+// ExpectQueryRegexp adds an expectation matching query text by the regular expression.
+func (m *Mock) ExpectQueryRegexp(expr string) *Expectation {
+	return m.expect(&Expectation{re: regexp.MustCompile(expr)})
+}
+
+// ExpectationsWereMet reports expectations which have not been called yet.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var unmet []string
+	for _, e := range m.expects {
+		if e.calls == 0 {
+			unmet = append(unmet, e.String())
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("unmet expectations: %s", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+func (m *Mock) expect(e *Expectation) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expects = append(m.expects, e)
+	return e
+}
+
+func (m *Mock) answer(query string, args []driver.NamedValue) (*ResultSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expects {
+		if e.match(query, args) {
+			e.calls++
+			if e.err != nil {
+				return nil, e.err
+			}
+			if e.rs == nil {
+				return &ResultSet{}, nil
+			}
+			return e.rs, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query: %q %v", query, namedValues(args))
+}
+
+// Expectation maps a query and its args to a result set or an error. Expectations are checked in order of being added,
+// and the first matched one is used.
+type Expectation struct {
+	query string
+	re    *regexp.Regexp
+	args  []driver.Value
+	rs    *ResultSet
+	err   error
+	times int
+	calls int
+}
+
+// WithArgs restricts the expectation to queries with the args, which are compared after being converted to driver
+// values. Any args are accepted if WithArgs is never called.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = make([]driver.Value, len(args))
+	for i, arg := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			panic(err)
+		}
+		e.args[i] = v
+	}
+	return e
+}
+
+// WillReturn answers matched queries with the result set.
+func (e *Expectation) WillReturn(rs *ResultSet) *Expectation {
+	e.rs = rs
+	return e
+}
+
+// WillReturnResult answers matched statements with the exec result.
+func (e *Expectation) WillReturnResult(res ExecResult) *Expectation {
+	e.rs = &ResultSet{exec: res}
+	return e
+}
+
+// WillReturnError fails matched queries with the error.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times limits the number of queries the expectation can answer, zero means no limit.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) String() string {
+	s := strconv.Quote(e.query)
+	if e.re != nil {
+		s = "/" + e.re.String() + "/"
+	}
+	if e.args != nil {
+		s += fmt.Sprint(e.args)
+	}
+	return s
+}
+
+func (e *Expectation) match(query string, args []driver.NamedValue) bool {
+	if e.times > 0 && e.calls >= e.times {
+		return false
+	}
+	if e.re != nil {
+		if !e.re.MatchString(query) {
+			return false
+		}
+	} else if e.query != query {
+		return false
+	}
+	if e.args == nil {
+		return true
+	}
+	return reflect.DeepEqual(e.args, namedValues(args))
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Value
+	}
+	return vs
+}
+
+type mockDriver struct{}
+
+func (d mockDriver) Open(name string) (driver.Conn, error) {
+	mocks.Lock()
+	defer mocks.Unlock()
+	m, ok := mocks.m[name]
+	if !ok {
+		return nil, fmt.Errorf("mock not found: %q", name)
+	}
+	return &mockConn{m}, nil
+}
+
+type mockConn struct{ m *Mock }
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) { return &mockStmt{c, query}, nil }
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Begin() (driver.Tx, error) { return mockTx{}, nil }
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rs, err := c.m.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &driverRows{columnDefs: columnDefs(rs.cols), rs: rs}, nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rs, err := c.m.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{rs.exec}, nil
+}
+
+type mockStmt struct {
+	conn  *mockConn
+	query string
+}
+
+func (s *mockStmt) Close() error { return nil }
+
+func (s *mockStmt) NumInput() int { return -1 }
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedArgs(args))
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedArgs(args))
+}
+
+func (s *mockStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *mockStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedArgs(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return nvs
+}
+
+type mockTx struct{}
+
+func (tx mockTx) Commit() error { return nil }
+
+func (tx mockTx) Rollback() error { return nil }
+
+// execResult implements sql.Result by an ExecResult.
+type execResult struct{ res ExecResult }
+
+func (r execResult) LastInsertId() (int64, error) {
+	if !r.res.HasLastInsertId {
+		return 0, errors.New("LastInsertId is not supported")
+	}
+	return r.res.LastInsertId, nil
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+	if !r.res.HasRowsAffected {
+		return 0, errors.New("RowsAffected is not supported")
+	}
+	return r.res.RowsAffected, nil
+}
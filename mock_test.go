@@ -0,0 +1,66 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, Nullable: true, HasNullable: true, HasLength: true},
+		{Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", "foo", "1.50"},
+		[]interface{}{"2", nil, "0.00"},
+	)
+
+	m := NewMock()
+	defer m.Close()
+	m.ExpectQuery("select * from t where id > ?").WithArgs(0).WillReturn(rs)
+	m.ExpectQueryRegexp(`^select \* from t`).WillReturnError(errors.New("boom"))
+	m.ExpectQuery("insert into t values (?, ?)").WithArgs(3, "bar").WillReturnResult(ExecResult{1, 3, true, true}).Times(1)
+	m.ExpectQueryRegexp(`^update`).WillReturnResult(ExecResult{RowsAffected: 2, HasRowsAffected: true})
+	require.EqualError(t, m.ExpectationsWereMet(), `unmet expectations: "select * from t where id > ?"[0], /^select \* from t/, "insert into t values (?, ?)"[3 bar], /^update/`)
+
+	db := m.DB()
+	defer db.Close()
+	ctx := context.Background()
+
+	rs2, err := FetchContext(ctx, db, "select * from t where id > ?", 0)
+	require.NoError(t, err)
+	require.NoError(t, Diff(rs, rs2, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+	require.Equal(t, rs.cols, rs2.cols)
+	require.Equal(t, rs.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+
+	_, err = FetchContext(ctx, db, "select * from t where id > ?", 1)
+	require.EqualError(t, err, "boom")
+	_, err = FetchContext(ctx, db, "select 1")
+	require.EqualError(t, err, `unexpected query: "select 1" []`)
+
+	stmts := WithStmtCache(db)
+	defer stmts.Reset()
+	res, err := stmts.ExecContext(ctx, "insert into t values (?, ?)", int8(3), "bar")
+	require.NoError(t, err)
+	require.Equal(t, ExecResult{1, 3, true, true}, NewFromResult(res).ExecResult())
+	_, err = stmts.ExecContext(ctx, "insert into t values (?, ?)", 3, "bar")
+	require.Error(t, err)
+
+	res, err = db.Exec("update t set name = ?", sql.NullString{})
+	require.NoError(t, err)
+	require.Equal(t, ExecResult{2, 0, true, false}, NewFromResult(res).ExecResult())
+	_, err = res.LastInsertId()
+	require.Error(t, err)
+
+	require.NoError(t, m.ExpectationsWereMet())
+
+	m.Close()
+	db2 := m.DB()
+	defer db2.Close()
+	require.EqualError(t, db2.PingContext(ctx), `mock not found: "`+m.DSN()+`"`)
+}
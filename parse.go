@@ -0,0 +1,186 @@
+package sqlz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseTable parses the output of the mysql client into a result set, either an ASCII table like
+//
+//	+----+------+
+//	| id | name |
+//	+----+------+
+//	|  1 | NULL |
+//	+----+------+
+//
+// or a tab-separated block (mysql -B) with a header line. NULL is recognized as the NULL value and column types are
+// left empty. Lines of an ASCII table may be indented, while a tab-separated block is used as is except surrounding
+// blank lines.
+func ParseTable(text string) (*ResultSet, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && len(strings.TrimSpace(lines[0])) == 0 {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && len(strings.TrimSpace(lines[len(lines)-1])) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("empty table")
+	}
+	if strings.HasPrefix(strings.TrimSpace(lines[0]), "+-") {
+		return parseASCIITable(lines)
+	}
+	return parseTSV(lines)
+}
+
+// ParseRows is like ParseTable but returns rows for AssertData, NULL values are nil and others are strings.
+func ParseRows(text string) (Rows, error) {
+	rs, err := ParseTable(text)
+	if err != nil {
+		return nil, err
+	}
+	rows := make(Rows, rs.NRows())
+	for i := range rows {
+		rows[i] = make([]interface{}, rs.NCols())
+		for j := range rows[i] {
+			if !rs.isNil(i, j) {
+				v, _ := rs.RawValue(i, j)
+				rows[i][j] = string(v)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func MustParseRows(text string) Rows {
+	rows, err := ParseRows(text)
+	if err != nil {
+		panic(err)
+	}
+	return rows
+}
+
+func parseASCIITable(lines []string) (*ResultSet, error) {
+	border := []rune(strings.TrimSpace(lines[0]))
+	var seps []int
+	for k, r := range border {
+		if r == '+' {
+			seps = append(seps, k)
+		}
+	}
+	if len(seps) < 2 || border[len(border)-1] != '+' {
+		return nil, fmt.Errorf("invalid table border: %q", string(border))
+	}
+	split := func(line string) ([]string, error) {
+		runes := []rune(line)
+		cells := make([]string, 0, len(seps)-1)
+		if len(runes) == len(border) {
+			for k := 1; k < len(seps); k++ {
+				if runes[seps[k]] != '|' {
+					cells = cells[:0]
+					break
+				}
+				cells = append(cells, string(runes[seps[k-1]+1:seps[k]]))
+			}
+		}
+		if len(cells) == 0 && len(line) >= 2 {
+			// cells are not aligned to the border (e.g. wide characters), fall back to splitting by bars.
+			cells = strings.Split(line[1:len(line)-1], "|")
+		}
+		if len(cells) != len(seps)-1 {
+			return nil, fmt.Errorf("expected %d cells, not %d: %q", len(seps)-1, len(cells), line)
+		}
+		for k := range cells {
+			cells[k] = strings.Trim(cells[k], " ")
+		}
+		return cells, nil
+	}
+
+	var rs *ResultSet
+	for n, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "+") {
+			continue
+		}
+		if !strings.HasPrefix(line, "|") || !strings.HasSuffix(line, "|") {
+			// the trailer like "1 row in set (0.00 sec)"
+			break
+		}
+		cells, err := split(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid table line#%d: %w", n+2, err)
+		}
+		if rs == nil {
+			cols := make([]ColumnDef, len(cells))
+			for j, name := range cells {
+				cols[j].Name = name
+			}
+			rs = New(cols)
+			continue
+		}
+		rs.appendTextRow(cells, false)
+	}
+	if rs == nil {
+		return nil, errors.New("missing table header")
+	}
+	return rs, nil
+}
+
+func parseTSV(lines []string) (*ResultSet, error) {
+	hdr := strings.Split(lines[0], "\t")
+	cols := make([]ColumnDef, len(hdr))
+	for j, name := range hdr {
+		cols[j].Name = unescapeTSV(name)
+	}
+	rs := New(cols)
+	for n, line := range lines[1:] {
+		cells := strings.Split(line, "\t")
+		if len(cells) != len(cols) {
+			return nil, fmt.Errorf("invalid table line#%d: expected %d cells, not %d: %q", n+2, len(cols), len(cells), line)
+		}
+		rs.appendTextRow(cells, true)
+	}
+	return rs, nil
+}
+
+func (rs *ResultSet) appendTextRow(cells []string, escaped bool) {
+	i, row := rs.NRows(), rs.AllocateRow()
+	for j, s := range cells {
+		if s == "NULL" {
+			rs.markNil(i, j)
+			continue
+		}
+		if escaped {
+			s = unescapeTSV(s)
+		}
+		*row[j].(*[]byte) = []byte(s)
+	}
+	rs.flush()
+}
+
+// unescapeTSV reverts the escaping of mysql batch mode, which writes \0, \t, \n and \\ for special characters.
+func unescapeTSV(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for k := 0; k < len(s); k++ {
+		if s[k] != '\\' || k+1 == len(s) {
+			b.WriteByte(s[k])
+			continue
+		}
+		k++
+		switch s[k] {
+		case '0':
+			b.WriteByte(0)
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[k])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package sqlz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	cols := []ColumnDef{{Name: "id"}, {Name: "name"}, {Name: "note"}}
+	expect := newTestResultSet(cols,
+		[]interface{}{"1", nil, "a b"},
+		[]interface{}{"2", "", "x|y"},
+		[]interface{}{"3", "中文", "c"},
+	)
+	for _, tt := range []string{`
+		+----+------+------+
+		| id | name | note |
+		+----+------+------+
+		|  1 | NULL | a b  |
+		|  2 |      | x|y  |
+		|  3 | 中文 | c    |
+		+----+------+------+
+		3 rows in set (0.00 sec)
+	`, "id\tname\tnote\n1\tNULL\ta b\n2\t\tx|y\n3\t中文\tc\n"} {
+		rs, err := ParseTable(tt)
+		require.NoError(t, err)
+		require.Equal(t, cols, rs.cols)
+		require.NoError(t, Diff(expect, rs, DiffOptions{CheckSchema: true}))
+		require.Equal(t, expect.DataDigest(DigestOptions{}), rs.DataDigest(DigestOptions{}))
+	}
+
+	rs, err := ParseTable("a\tb\nx\\ty\t\\\\N\n")
+	require.NoError(t, err)
+	require.NoError(t, rs.AssertData(Rows{{"x\ty", `\N`}}))
+
+	rows := MustParseRows(`
+		+---+------+
+		| a | b    |
+		+---+------+
+		| 1 | NULL |
+		+---+------+
+	`)
+	require.Equal(t, Rows{{"1", nil}}, rows)
+	rs, err = ParseTable("+---+\n| a |\n+---+\n")
+	require.NoError(t, err)
+	require.Equal(t, 0, rs.NRows())
+
+	_, err = ParseTable(" \n")
+	require.EqualError(t, err, "empty table")
+	_, err = ParseTable("a\tb\n1\n")
+	require.EqualError(t, err, `invalid table line#2: expected 2 cells, not 1: "1"`)
+	_, err = ParseTable("+---+---+\n| a | b | c |\n")
+	require.EqualError(t, err, `invalid table line#2: expected 2 cells, not 3: "| a | b | c |"`)
+	_, err = ParseTable("+-+\n|\n")
+	require.EqualError(t, err, `invalid table line#2: expected 1 cells, not 0: "|"`)
+}
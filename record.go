@@ -0,0 +1,194 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Record is an entry of a recorded session, Result is encoded by ResultSet.Encode.
+type Record struct {
+	Query    string
+	Args     []string
+	Result   []byte
+	Err      string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Recorder wraps a ConnContext (like *sql.DB, *sql.Conn and *StmtPool) and writes every query with its result to w as
+// a stream of gob encoded records, which can be served by Replayer later.
+type Recorder struct {
+	conn ConnContext
+	mu   sync.Mutex
+	enc  *gob.Encoder
+	err  error
+}
+
+func NewRecorder(conn ConnContext, w io.Writer) *Recorder {
+	return &Recorder{conn: conn, enc: gob.NewEncoder(w)}
+}
+
+// Err returns the first error occurred on writing records.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+func (r *Recorder) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext reads all rows of the query into a result set, then records and replays it.
+func (r *Recorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.record(query, args, nil, err, start)
+		return nil, err
+	}
+	rs, err := ReadFromRows(rows)
+	rows.Close()
+	r.record(query, args, rs, err, start)
+	if err != nil {
+		return nil, err
+	}
+	return rs.sqlRows()
+}
+
+func (r *Recorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.record(query, args, nil, err, start)
+		return nil, err
+	}
+	r.record(query, args, NewFromResult(res), nil, start)
+	return res, nil
+}
+
+func (r *Recorder) record(query string, args []interface{}, rs *ResultSet, err error, start time.Time) {
+	rec := Record{Query: query, Args: formatArgs(args), Start: start, Duration: time.Since(start)}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if rs != nil {
+		if rec.Result, r.err = rs.Encode(); r.err != nil {
+			return
+		}
+	}
+	r.err = r.enc.Encode(rec)
+}
+
+// Replayer serves queries by records written by Recorder. A query is answered by the first unused record with the same
+// query text and args, so repeated queries are answered in the recorded order.
+type Replayer struct {
+	mu      sync.Mutex
+	records []Record
+	used    []bool
+}
+
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	p := &Replayer{}
+	dec := gob.NewDecoder(r)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		p.records = append(p.records, rec)
+	}
+	p.used = make([]bool, len(p.records))
+	return p, nil
+}
+
+// Records returns all records in the recorded order.
+func (p *Replayer) Records() []Record { return p.records }
+
+func (p *Replayer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+func (p *Replayer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+func (p *Replayer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rs, err := p.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return rs.sqlRows()
+}
+
+func (p *Replayer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	rs, err := p.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{rs.exec}, nil
+}
+
+func (p *Replayer) answer(query string, args []interface{}) (*ResultSet, error) {
+	fargs := formatArgs(args)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, rec := range p.records {
+		if p.used[i] || rec.Query != query || !equalStrings(rec.Args, fargs) {
+			continue
+		}
+		p.used[i] = true
+		if len(rec.Err) > 0 {
+			return nil, errors.New(rec.Err)
+		}
+		rs := &ResultSet{}
+		if err := rs.Decode(rec.Result); err != nil {
+			return nil, err
+		}
+		return rs, nil
+	}
+	return nil, fmt.Errorf("no recorded answer: %q %v", query, fargs)
+}
+
+// formatArgs formats args after converting them to driver values, so that args like int(1) and int64(1) are identical.
+func formatArgs(args []interface{}) []string {
+	xs := make([]string, len(args))
+	for i, arg := range args {
+		if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			xs[i] = fmt.Sprintf("%T:%v", v, v)
+		} else {
+			xs[i] = fmt.Sprintf("%T:%v", arg, arg)
+		}
+	}
+	return xs
+}
+
+func equalStrings(xs []string, ys []string) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if xs[i] != ys[i] {
+			return false
+		}
+	}
+	return true
+}
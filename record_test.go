@@ -0,0 +1,56 @@
+package sqlz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, Nullable: true, HasNullable: true, HasLength: true},
+	}
+	rs1 := newTestResultSet(cols, []interface{}{"1", "foo"}, []interface{}{"2", nil})
+	rs2 := newTestResultSet(cols, []interface{}{"1", "bar"})
+
+	m := NewMock()
+	defer m.Close()
+	m.ExpectQuery("select * from t where id > ?").WithArgs(0).WillReturn(rs1).Times(1)
+	m.ExpectQuery("select * from t where id > ?").WithArgs(0).WillReturn(rs2).Times(1)
+	m.ExpectQuery("select * from t where id > ?").WithArgs(1).WillReturnError(errors.New("boom"))
+	m.ExpectQuery("delete from t").WillReturnResult(ExecResult{RowsAffected: 2, HasRowsAffected: true})
+	db := m.DB()
+	defer db.Close()
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	r := NewRecorder(WithStmtCache(db), &buf)
+	check := func(c ConnContext) {
+		rs, err := FetchContext(ctx, c, "select * from t where id > ?", 0)
+		require.NoError(t, err)
+		require.NoError(t, Diff(rs1, rs, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+		rs, err = FetchContext(ctx, c, "select * from t where id > ?", int64(0))
+		require.NoError(t, err)
+		require.NoError(t, Diff(rs2, rs, DiffOptions{CheckSchema: true, CheckPrecision: true}))
+		_, err = FetchContext(ctx, c, "select * from t where id > ?", 1)
+		require.EqualError(t, err, "boom")
+		res, err := c.ExecContext(ctx, "delete from t")
+		require.NoError(t, err)
+		require.Equal(t, ExecResult{RowsAffected: 2, HasRowsAffected: true}, NewFromResult(res).ExecResult())
+	}
+	check(r)
+	require.NoError(t, r.Err())
+	require.NoError(t, m.ExpectationsWereMet())
+
+	p, err := NewReplayer(&buf)
+	require.NoError(t, err)
+	require.Len(t, p.Records(), 4)
+	require.Equal(t, []string{"int64:1"}, p.Records()[2].Args)
+	check(p)
+	_, err = p.Query("select * from t where id > ?", 0)
+	require.EqualError(t, err, `no recorded answer: "select * from t where id > ?" [int64:0]`)
+}
@@ -0,0 +1,317 @@
+package sqlz
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type RenderOptions struct {
+	// SideBySide renders two result sets in two columns instead of the unified format.
+	SideBySide bool
+	// OnlyDiffs omits rows without differences.
+	OnlyDiffs bool
+	// MaxRows limits the number of rendered rows, zero means no limit.
+	MaxRows int
+	// MaxWidth limits the width of rendered cells, zero means no limit.
+	MaxWidth int
+}
+
+// RenderDiff writes a human-readable diff of two result sets to w. Rows and columns are matched by the same rules of
+// Diff, mismatched cells are marked by asterisks.
+func RenderDiff(w io.Writer, rs1 *ResultSet, rs2 *ResultSet, opts DiffOptions, ropts RenderOptions) error {
+	bw := bufio.NewWriter(w)
+	r, a := diffAligned(rs1, rs2, opts, false)
+	fmt.Fprintf(bw, "--- left: %s\n+++ right: %s\n", rs1.String(), rs2.String())
+	if err := r.Err(); err != nil {
+		fmt.Fprintf(bw, "# %s\n", err)
+	}
+	for _, d := range r.Schema {
+		fmt.Fprintf(bw, "# schema mismatch: %s\n", d.String())
+	}
+	if len(r.LeftOnlyCols) > 0 {
+		fmt.Fprintf(bw, "# columns only in left: %s\n", strings.Join(columnNames(rs1.cols, r.LeftOnlyCols), ", "))
+	}
+	if len(r.RightOnlyCols) > 0 {
+		fmt.Fprintf(bw, "# columns only in right: %s\n", strings.Join(columnNames(rs2.cols, r.RightOnlyCols), ", "))
+	}
+
+	var dl *diffLines
+	if rs1.IsExecResult() && rs2.IsExecResult() {
+		dl = execDiffLines(rs1.exec, rs2.exec, opts)
+	} else if a != nil {
+		dl = rowDiffLines(rs1, rs2, r, a, ropts)
+	}
+	if dl != nil {
+		if ropts.SideBySide {
+			dl.renderSideBySide(bw, ropts.MaxWidth)
+		} else {
+			dl.renderUnified(bw, ropts.MaxWidth)
+		}
+		if dl.omitted > 0 {
+			fmt.Fprintf(bw, "... %d more rows\n", dl.omitted)
+		}
+	}
+	return bw.Flush()
+}
+
+type diffLine struct {
+	left  []string
+	right []string
+	hl    []bool
+}
+
+func (l diffLine) changed() bool { return l.left == nil || l.right == nil || l.hl != nil }
+
+type diffLines struct {
+	hdr     [2][]string
+	lines   []diffLine
+	omitted int
+}
+
+func execDiffLines(e1 ExecResult, e2 ExecResult, opts DiffOptions) *diffLines {
+	hdr := []string{"RowsAffected", "LastInsertId"}
+	dl := &diffLines{hdr: [2][]string{hdr, hdr}}
+	format := func(e ExecResult) []string {
+		row := []string{"NULL", "NULL"}
+		if e.HasRowsAffected {
+			row[0] = strconv.FormatInt(e.RowsAffected, 10)
+		}
+		if e.HasLastInsertId {
+			row[1] = strconv.FormatInt(e.LastInsertId, 10)
+		}
+		return row
+	}
+	line := diffLine{left: format(e1), right: format(e2)}
+	for i, eq := range []bool{opts.equalRowsAffected(e1, e2), opts.equalLastInsertId(e1, e2)} {
+		if !eq {
+			if line.hl == nil {
+				line.hl = make([]bool, len(line.left))
+			}
+			line.hl[i] = true
+		}
+	}
+	dl.lines = append(dl.lines, line)
+	return dl
+}
+
+func rowDiffLines(rs1 *ResultSet, rs2 *ResultSet, r *DiffReport, a *alignment, ropts RenderOptions) *diffLines {
+	dl := &diffLines{hdr: [2][]string{
+		columnNames(rs1.cols, lefts(a.cols)),
+		columnNames(rs2.cols, rights(a.cols)),
+	}}
+	format := func(rs *ResultSet, i int, cols []int) []string {
+		row := make([]string, len(cols))
+		for k, j := range cols {
+			v, _ := rs.RawValue(i, j)
+			row[k] = formatValue(v, rs.isNil(i, j))
+		}
+		return row
+	}
+	colIndex := make(map[int]int, len(a.cols))
+	for k, c := range a.cols {
+		colIndex[c.left] = k
+	}
+	mismatched := make(map[int][]bool)
+	for _, d := range r.Cells {
+		hl := mismatched[d.Row]
+		if hl == nil {
+			hl = make([]bool, len(a.cols))
+			mismatched[d.Row] = hl
+		}
+		hl[colIndex[d.Col]] = true
+	}
+	paired := make(map[int]int, len(a.rows))
+	for _, p := range a.rows {
+		paired[p.left] = p.right
+	}
+
+	// add checks the limits before building the line, so that omitted rows are never formatted.
+	add := func(changed bool, build func() diffLine) {
+		if ropts.OnlyDiffs && !changed {
+			return
+		}
+		if ropts.MaxRows > 0 && len(dl.lines) >= ropts.MaxRows {
+			dl.omitted++
+			return
+		}
+		dl.lines = append(dl.lines, build())
+	}
+	leftCols, rightCols := lefts(a.cols), rights(a.cols)
+	for i := 0; i < rs1.NRows(); i++ {
+		k, ok := paired[i]
+		add(!ok || mismatched[i] != nil, func() diffLine {
+			line := diffLine{left: format(rs1, i, leftCols)}
+			if ok {
+				line.right = format(rs2, k, rightCols)
+				line.hl = mismatched[i]
+			}
+			return line
+		})
+	}
+	for _, k := range a.rightRows {
+		add(true, func() diffLine { return diffLine{right: format(rs2, k, rightCols)} })
+	}
+	return dl
+}
+
+func (dl *diffLines) renderUnified(w io.Writer, maxWidth int) {
+	type row struct {
+		mark  string
+		cells []string
+	}
+	rows := []row{{" ", dl.hdr[0]}}
+	side := func(mark string, cells []string, hl []bool) row {
+		out := make([]string, len(cells))
+		for j, c := range cells {
+			out[j] = truncateCell(c, maxWidth)
+			if hl != nil && hl[j] {
+				out[j] = "*" + out[j] + "*"
+			}
+		}
+		return row{mark, out}
+	}
+	for _, l := range dl.lines {
+		if !l.changed() {
+			rows = append(rows, side(" ", l.left, nil))
+			continue
+		}
+		if l.left != nil {
+			rows = append(rows, side("-", l.left, l.hl))
+		}
+		if l.right != nil {
+			rows = append(rows, side("+", l.right, l.hl))
+		}
+	}
+	widths := make([]int, len(dl.hdr[0]))
+	for _, r := range rows {
+		for j, c := range r.cells {
+			widths[j] = maxInt(widths[j], utf8.RuneCountInString(c))
+		}
+	}
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.TrimRight(r.mark+" "+joinCells(r.cells, widths), " "))
+	}
+}
+
+func (dl *diffLines) renderSideBySide(w io.Writer, maxWidth int) {
+	type row struct {
+		mark  string
+		cells [2][]string
+	}
+	rows := []row{{" ", dl.hdr}}
+	side := func(cells []string, hl []bool) []string {
+		if cells == nil {
+			return nil
+		}
+		out := make([]string, len(cells))
+		for j, c := range cells {
+			out[j] = truncateCell(c, maxWidth)
+			if hl != nil && hl[j] {
+				out[j] = "*" + out[j] + "*"
+			}
+		}
+		return out
+	}
+	for _, l := range dl.lines {
+		mark := " "
+		if l.right == nil {
+			mark = "<"
+		} else if l.left == nil {
+			mark = ">"
+		} else if l.hl != nil {
+			mark = "|"
+		}
+		rows = append(rows, row{mark, [2][]string{side(l.left, l.hl), side(l.right, l.hl)}})
+	}
+	var widths [2][]int
+	for s := range widths {
+		widths[s] = make([]int, len(dl.hdr[s]))
+		for _, r := range rows {
+			for j, c := range r.cells[s] {
+				widths[s][j] = maxInt(widths[s][j], utf8.RuneCountInString(c))
+			}
+		}
+	}
+	for _, r := range rows {
+		left := joinCells(r.cells[0], widths[0])
+		if r.cells[0] == nil {
+			left = joinCells(make([]string, len(widths[0])), widths[0])
+		}
+		right := ""
+		if r.cells[1] != nil {
+			right = joinCells(r.cells[1], widths[1])
+		}
+		fmt.Fprintln(w, strings.TrimRight(left+" "+r.mark+" "+right, " "))
+	}
+}
+
+func joinCells(cells []string, widths []int) string {
+	var b strings.Builder
+	for j, c := range cells {
+		if j > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(c)
+		b.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(c)))
+	}
+	return b.String()
+}
+
+func truncateCell(s string, maxWidth int) string {
+	if maxWidth <= 0 || utf8.RuneCountInString(s) <= maxWidth {
+		return s
+	}
+	if maxWidth <= 3 {
+		return string([]rune(s)[:maxWidth])
+	}
+	return string([]rune(s)[:maxWidth-3]) + "..."
+}
+
+// formatValue formats a raw value for display, NULL is rendered as a bare NULL while strings that could be confused
+// with it are quoted, and binary values are rendered as hex literals.
+func formatValue(v []byte, isNil bool) string {
+	if isNil {
+		return "NULL"
+	}
+	if !isPrintable(v) {
+		return "0x" + hex.EncodeToString(v)
+	}
+	s := string(v)
+	if s == "" || strings.EqualFold(s, "NULL") || strings.TrimSpace(s) != s || strings.HasPrefix(s, "'") {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return s
+}
+
+func isPrintable(v []byte) bool {
+	if !utf8.Valid(v) {
+		return false
+	}
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func columnNames(cols []ColumnDef, idxs []int) []string {
+	names := make([]string, len(idxs))
+	for i, j := range idxs {
+		names[i] = cols[j].Name
+	}
+	return names
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package sqlz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDiff(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", ""},
+		[]interface{}{"3", nil},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"1", "xyz"},
+		[]interface{}{"2", ""},
+		[]interface{}{"3", "NULL"},
+		[]interface{}{"4", []byte{0, 1}},
+	)
+	for i, tt := range []struct {
+		opts   DiffOptions
+		ropts  RenderOptions
+		output string
+	}{
+		{ropts: RenderOptions{}, output: `
+--- left: 3 rows in set
++++ right: 4 rows in set
+# row count mismatch: 3 <> 4
+  id | name
+- 1  | *a*
++ 1  | *xyz*
+  2  | ''
+- 3  | *NULL*
++ 3  | *'NULL'*
++ 4  | 0x0001
+`},
+		{ropts: RenderOptions{SideBySide: true}, output: `
+--- left: 3 rows in set
++++ right: 4 rows in set
+# row count mismatch: 3 <> 4
+id | name     id | name
+1  | *a*    | 1  | *xyz*
+2  | ''       2  | ''
+3  | *NULL* | 3  | *'NULL'*
+   |        > 4  | 0x0001
+`},
+		{opts: DiffOptions{KeyIndexes: []int{1}}, ropts: RenderOptions{OnlyDiffs: true, MaxRows: 2, MaxWidth: 4}, output: `
+--- left: 3 rows in set
++++ right: 4 rows in set
+# row count mismatch: 3 <> 4
+  id | name
+- 1  | a
+- 3  | NULL
+... 3 more rows
+`},
+	} {
+		var out strings.Builder
+		require.NoError(t, RenderDiff(&out, rs1, rs2, tt.opts, tt.ropts))
+		require.Equal(t, strings.TrimPrefix(tt.output, "\n"), out.String(), "#%d", i)
+	}
+
+	var out strings.Builder
+	require.NoError(t, RenderDiff(&out, &rss[1], &rss[3], DiffOptions{}, RenderOptions{}))
+	require.Equal(t, "--- left: 1 rows affected\n+++ right: 3 rows in set\n# result type mismatch: 1 rows affected <> 3 rows in set\n", out.String())
+	out.Reset()
+	require.NoError(t, RenderDiff(&out, &rss[1], &rss[0], DiffOptions{}, RenderOptions{}))
+	require.Equal(t, `--- left: 1 rows affected
++++ right: 0 rows affected
+# execute result mismatch: {1 0 true false} <> {0 0 false false}
+  RowsAffected | LastInsertId
+- *1*          | NULL
++ *NULL*       | NULL
+`, out.String())
+}
@@ -1,19 +1,26 @@
 package sqlz
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
-	"crypto/sha1"
 	"database/sql"
 	"encoding/binary"
 	"encoding/gob"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"sort"
 	"strconv"
+	"strings"
 	"unicode"
+	"unsafe"
+)
+
+var (
+	ErrColumnNotFound  = errors.New("column not found")
+	ErrAmbiguousColumn = errors.New("ambiguous column")
 )
 
 type TableFormatter interface {
@@ -44,11 +51,21 @@ type ExecResult struct {
 	HasLastInsertId bool
 }
 
+// ResultSet stores values column by column, each column keeps its values in a contiguous buffer with end offsets, and
+// NULL values are marked in a bitmap shared by all columns.
 type ResultSet struct {
-	cols []ColumnDef
-	data [][][]byte
-	nils []uint64
-	exec ExecResult
+	cols  []ColumnDef
+	data  []column
+	nrows int
+	// staged holds rows returned by AllocateRow, which stay addressable until they are moved into data by flush.
+	staged [][][]byte
+	nils   []uint64
+	exec   ExecResult
+}
+
+type column struct {
+	buf  []byte
+	ends []int
 }
 
 func New(schema []ColumnDef) *ResultSet {
@@ -66,6 +83,38 @@ func NewFromResult(res sql.Result) *ResultSet {
 }
 
 func ReadFromRows(rows RowIterator) (*ResultSet, error) {
+	cols, err := readColumnDefs(rows)
+	if err != nil {
+		return nil, err
+	}
+	rs := New(cols)
+	for rows.Next() {
+		if err = rs.scanRow(rows); err != nil {
+			return rs, err
+		}
+	}
+	return rs, rows.Err()
+}
+
+// scanRow appends the current row of rows.
+func (rs *ResultSet) scanRow(rows RowIterator) error {
+	i, row := rs.NRows(), rs.AllocateRow()
+	if err := rows.Scan(row...); err != nil {
+		return err
+	}
+	for j, col := range row {
+		if *col.(*[]byte) == nil {
+			rs.markNil(i, j)
+		}
+	}
+	rs.flush()
+	return nil
+}
+
+func readColumnDefs(rows RowIterator) ([]ColumnDef, error) {
+	if r, ok := rows.(*ResultRows); ok {
+		return append([]ColumnDef(nil), r.ColumnDefs()...), nil
+	}
 	types, err := rows.ColumnTypes()
 	if err != nil {
 		return nil, err
@@ -78,37 +127,16 @@ func ReadFromRows(rows RowIterator) (*ResultSet, error) {
 		cols[i].Length, cols[i].HasLength = t.Length()
 		cols[i].Precision, cols[i].Scale, cols[i].HasPrecisionScale = t.DecimalSize()
 	}
-	rs, i := New(cols), 0
-	for rows.Next() {
-		row := rs.AllocateRow()
-		if err = rows.Scan(row...); err != nil {
-			return rs, err
-		}
-		for j, col := range row {
-			if *col.(*[]byte) == nil {
-				rs.markNil(i, j)
-			}
-		}
-		i += 1
-	}
-	return rs, rows.Err()
+	return cols, nil
 }
 
-func (rs *ResultSet) String() string {
-	if rs.IsExecResult() {
-		return strconv.FormatInt(rs.ExecResult().RowsAffected, 10) + " rows affected"
-	}
-	if rs.NRows() == 0 {
-		return "empty set"
-	}
-	return strconv.Itoa(rs.NRows()) + " rows in set"
-}
+func (rs *ResultSet) String() string { return describeResult(rs.NCols(), rs.NRows(), rs.exec) }
 
 func (rs *ResultSet) IsExecResult() bool { return len(rs.cols) == 0 }
 
 func (rs *ResultSet) ExecResult() ExecResult { return rs.exec }
 
-func (rs *ResultSet) NRows() int { return len(rs.data) }
+func (rs *ResultSet) NRows() int { return rs.nrows }
 
 func (rs *ResultSet) NCols() int { return len(rs.cols) }
 
@@ -122,27 +150,88 @@ func (rs *ResultSet) ColumnDef(i int) ColumnDef {
 	return rs.cols[i]
 }
 
-func (rs *ResultSet) Sort(less func(r1 int, r2 int) bool) { sort.SliceStable(rs.data, less) }
+// ColumnIndex returns the index of the named column. Names are matched exactly first and then case-insensitively, and a
+// qualified name like t.c falls back to matching c. It fails with ErrColumnNotFound if no column matches, or with
+// ErrAmbiguousColumn if multiple columns match.
+func (rs *ResultSet) ColumnIndex(name string) (int, error) {
+	idxs := rs.ColumnIndexes(name)
+	if len(idxs) == 0 {
+		return -1, fmt.Errorf("%w: %q", ErrColumnNotFound, name)
+	}
+	if len(idxs) > 1 {
+		return -1, fmt.Errorf("%w: %q matches cols %v", ErrAmbiguousColumn, name, idxs)
+	}
+	return idxs[0], nil
+}
+
+// ColumnIndexes returns indexes of all columns matching the name by the rules of ColumnIndex.
+func (rs *ResultSet) ColumnIndexes(name string) []int {
+	for {
+		for _, eq := range []func(string, string) bool{
+			func(s, t string) bool { return s == t },
+			strings.EqualFold,
+		} {
+			var idxs []int
+			for i, c := range rs.cols {
+				if eq(c.Name, name) {
+					idxs = append(idxs, i)
+				}
+			}
+			if len(idxs) > 0 {
+				return idxs
+			}
+		}
+		pos := strings.IndexByte(name, '.')
+		if pos < 0 {
+			return nil
+		}
+		name = name[pos+1:]
+	}
+}
+
+// ValueByName is like RawValue but addresses the column by name.
+func (rs *ResultSet) ValueByName(i int, name string) ([]byte, error) {
+	j, err := rs.ColumnIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := rs.RawValue(i, j)
+	if !ok {
+		return nil, fmt.Errorf("row index out of range: %d", i)
+	}
+	return v, nil
+}
+
+// Sort sorts rows stably, the arguments of less are indexes of rows before sorting. Values must be set before sorting,
+// since rows returned by AllocateRow are no longer addressable afterwards.
+func (rs *ResultSet) Sort(less func(r1 int, r2 int) bool) {
+	perm := make([]int, rs.nrows)
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(a, b int) bool { return less(perm[a], perm[b]) })
+	sorted := New(rs.cols)
+	sorted.exec = rs.exec
+	for _, i := range perm {
+		sorted.appendRaw(rs.rawRow(i))
+	}
+	*rs = *sorted
+}
 
 func (rs *ResultSet) RawValue(i int, j int) ([]byte, bool) {
 	if i < 0 {
-		i += len(rs.data)
+		i += rs.nrows
 	}
-	if i < 0 || i >= len(rs.data) {
+	if i < 0 || i >= rs.nrows {
 		return nil, false
 	}
-	row := rs.data[i]
 	if j < 0 {
-		j += len(row)
+		j += len(rs.cols)
 	}
-	if j < 0 || j >= len(row) {
+	if j < 0 || j >= len(rs.cols) {
 		return nil, false
 	}
-	v := rs.data[i][j]
-	if v == nil && !rs.isNil(i, j) {
-		return []byte{}, true
-	}
-	return v, true
+	return rs.value(i, j), true
 }
 
 func (rs *ResultSet) AllocateRow() []interface{} {
@@ -150,7 +239,8 @@ func (rs *ResultSet) AllocateRow() []interface{} {
 		return nil
 	}
 	row := make([][]byte, len(rs.cols))
-	rs.data = append(rs.data, row)
+	rs.staged = append(rs.staged, row)
+	rs.nrows++
 	xs := make([]interface{}, len(row))
 	for i := range row {
 		xs[i] = &row[i]
@@ -158,45 +248,46 @@ func (rs *ResultSet) AllocateRow() []interface{} {
 	return xs
 }
 
+// MemoryUsage returns the approximate number of bytes held by the result set.
+func (rs *ResultSet) MemoryUsage() int {
+	n := int(unsafe.Sizeof(*rs))
+	n += cap(rs.cols) * int(unsafe.Sizeof(ColumnDef{}))
+	for _, c := range rs.cols {
+		n += len(c.Name) + len(c.Type)
+	}
+	n += cap(rs.data) * int(unsafe.Sizeof(column{}))
+	for _, c := range rs.data {
+		n += cap(c.buf) + cap(c.ends)*int(unsafe.Sizeof(int(0)))
+	}
+	n += cap(rs.staged) * int(unsafe.Sizeof([][]byte{}))
+	for _, row := range rs.staged {
+		n += cap(row) * int(unsafe.Sizeof([]byte{}))
+		for _, v := range row {
+			n += cap(v)
+		}
+	}
+	n += cap(rs.nils) * 8
+	return n
+}
+
 func (rs *ResultSet) DataDigest(opts DigestOptions) string {
 	if rs.IsExecResult() {
 		return ""
 	}
-	if opts.Sort {
-		return rs.sortedDigest(opts)
+	d := NewDigester(rs.cols, opts)
+	for i := 0; i < rs.nrows; i++ {
+		d.Add(rs.rawRow(i))
 	}
-	h := sha1.New()
-	for i, row := range rs.data {
-		for j, v := range row {
-			if opts.Filter != nil && !opts.Filter(i, j, v, rs.cols[j]) {
-				continue
-			}
-			_ = rs.encodeCellTo(h, i, j, opts.Mapper)
-		}
-	}
-	return hex.EncodeToString(h.Sum(nil))
+	return d.Sum()
 }
 
-func (rs *ResultSet) sortedDigest(opts DigestOptions) string {
-	digests := make([][]byte, rs.NRows())
-	for i, row := range rs.data {
-		h := sha1.New()
-		for j, v := range row {
-			if opts.Filter != nil && !opts.Filter(i, j, v, rs.cols[j]) {
-				continue
-			}
-			_ = rs.encodeCellTo(h, i, j, opts.Mapper)
-		}
-		digests[i] = h.Sum(nil)
-	}
-	sort.Slice(digests, func(i, j int) bool {
-		return bytes.Compare(digests[i], digests[j]) < 0
-	})
-	h := sha1.New()
-	for _, digest := range digests {
-		h.Write(digest)
+// rawRow returns values of the i-th row, NULL values are nil.
+func (rs *ResultSet) rawRow(i int) [][]byte {
+	row := make([][]byte, len(rs.cols))
+	for j := range row {
+		row[j] = rs.value(i, j)
 	}
-	return hex.EncodeToString(h.Sum(nil))
+	return row
 }
 
 func (rs *ResultSet) AssertData(expect Rows, onErr ...func(act *ResultSet, exp Rows, err error)) (err error) {
@@ -211,7 +302,7 @@ func (rs *ResultSet) AssertData(expect Rows, onErr ...func(act *ResultSet, exp R
 		err = fmt.Errorf("row count mismatch: %d <> %d", rs.NRows(), len(expect))
 		return
 	}
-	for i, row := range rs.data {
+	for i := 0; i < rs.nrows; i++ {
 		if len(expect[i]) != rs.NCols() {
 			err = fmt.Errorf("invalid expected data: there are %d cols at %d row", len(expect[i]), i)
 			return
@@ -220,7 +311,7 @@ func (rs *ResultSet) AssertData(expect Rows, onErr ...func(act *ResultSet, exp R
 			if isNil := rs.isNil(i, j); exp == nil && isNil {
 				continue
 			} else if exp == nil {
-				err = fmt.Errorf("data mismatch (%q#%d): expect <nil> but got %v", rs.cols[j].Name, i, row[j])
+				err = fmt.Errorf("data mismatch (%q#%d): expect <nil> but got %v", rs.cols[j].Name, i, rs.value(i, j))
 				return
 			} else if isNil {
 				err = fmt.Errorf("data mismatch (%q#%d): expect %v but got <nil>", rs.cols[j].Name, i, exp)
@@ -269,6 +360,13 @@ func (rs *ResultSet) Dump(formatter TableFormatter) {
 		if rs.exec.HasLastInsertId {
 			row[1] = strconv.FormatInt(rs.exec.LastInsertId, 10)
 		}
+		if raw, ok := formatter.(RawTableFormatter); ok {
+			raw.AppendRaw([][]byte{
+				rawExecValue(row[0], rs.exec.HasRowsAffected),
+				rawExecValue(row[1], rs.exec.HasLastInsertId),
+			})
+			return
+		}
 		formatter.Append(row)
 	} else {
 		hdr := make([]string, len(rs.cols))
@@ -276,13 +374,19 @@ func (rs *ResultSet) Dump(formatter TableFormatter) {
 			hdr[i] = c.Name
 		}
 		formatter.SetHeader(hdr)
-		for i, r := range rs.data {
-			row := make([]string, len(r))
-			for j, s := range r {
+		if raw, ok := formatter.(RawTableFormatter); ok {
+			for i := 0; i < rs.nrows; i++ {
+				raw.AppendRaw(rs.rawRow(i))
+			}
+			return
+		}
+		for i := 0; i < rs.nrows; i++ {
+			row := make([]string, len(rs.cols))
+			for j := range row {
 				if rs.isNil(i, j) {
 					row[j] = "NULL"
 				} else {
-					row[j] = string(s)
+					row[j] = string(rs.value(i, j))
 				}
 			}
 			formatter.Append(row)
@@ -290,6 +394,13 @@ func (rs *ResultSet) Dump(formatter TableFormatter) {
 	}
 }
 
+func rawExecValue(s string, ok bool) []byte {
+	if !ok {
+		return nil
+	}
+	return []byte(s)
+}
+
 func (rs *ResultSet) Encode() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	if err := rs.EncodeTo(buf); err != nil {
@@ -298,7 +409,33 @@ func (rs *ResultSet) Encode() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// EncodeTo writes the result set in the versioned binary format with gzip, see EncodeWith.
 func (rs *ResultSet) EncodeTo(w io.Writer) error {
+	return rs.EncodeWith(w, EncodeOptions{Codec: CodecGzip})
+}
+
+func (rs *ResultSet) Decode(raw []byte) error {
+	return rs.DecodeFrom(bytes.NewReader(raw))
+}
+
+// DecodeFrom reads a result set written by EncodeTo or EncodeWith, it also reads the legacy gob format inside gzip.
+func (rs *ResultSet) DecodeFrom(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(formatMagic))
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if string(magic) == formatMagic {
+		return rs.decode(br)
+	}
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		return rs.decodeLegacy(br)
+	}
+	return fmt.Errorf("%w: bad magic %q", ErrUnsupportedFormat, magic)
+}
+
+// encodeLegacy writes the legacy format, which is an anonymous gob struct inside gzip.
+func (rs *ResultSet) encodeLegacy(w io.Writer) error {
 	zw := gzip.NewWriter(w)
 	defer zw.Close()
 	enc := gob.NewEncoder(zw)
@@ -307,15 +444,14 @@ func (rs *ResultSet) EncodeTo(w io.Writer) error {
 		Data [][][]byte
 		Nils []uint64
 		Exec ExecResult
-	}{rs.cols, rs.data, rs.nils, rs.exec}
+	}{rs.cols, make([][][]byte, rs.nrows), rs.nils, rs.exec}
+	for i := range tmp.Data {
+		tmp.Data[i] = rs.rawRow(i)
+	}
 	return enc.Encode(tmp)
 }
 
-func (rs *ResultSet) Decode(raw []byte) error {
-	return rs.DecodeFrom(bytes.NewReader(raw))
-}
-
-func (rs *ResultSet) DecodeFrom(r io.Reader) error {
+func (rs *ResultSet) decodeLegacy(r io.Reader) error {
 	zr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
@@ -330,10 +466,80 @@ func (rs *ResultSet) DecodeFrom(r io.Reader) error {
 	if err := dec.Decode(&tmp); err != nil {
 		return err
 	}
-	rs.cols, rs.data, rs.nils, rs.exec = tmp.Cols, tmp.Data, tmp.Nils, tmp.Exec
+	*rs = *newRawResultSet(tmp.Cols, tmp.Data, tmp.Nils, tmp.Exec)
 	return nil
 }
 
+// newRawResultSet builds a result set from rows of values and the bitmap of NULL values, which is the layout of the
+// legacy format.
+func newRawResultSet(cols []ColumnDef, data [][][]byte, nils []uint64, exec ExecResult) *ResultSet {
+	rs := &ResultSet{cols: cols, exec: exec}
+	for _, row := range data {
+		rs.appendRaw(row)
+	}
+	rs.nils = nils
+	return rs
+}
+
+// appendRaw appends a row where NULL values are nil.
+func (rs *ResultSet) appendRaw(row [][]byte) {
+	i, cells := rs.NRows(), rs.AllocateRow()
+	for j, v := range row {
+		if v == nil {
+			rs.markNil(i, j)
+		} else {
+			*cells[j].(*[]byte) = v
+		}
+	}
+	rs.flush()
+}
+
+// flush moves staged rows into column buffers. It's only called by loaders which fill rows at once, so that rows
+// allocated by callers of AllocateRow stay writable, and reads never change the result set.
+func (rs *ResultSet) flush() {
+	if len(rs.staged) == 0 {
+		return
+	}
+	if rs.data == nil {
+		rs.data = make([]column, len(rs.cols))
+	}
+	for _, row := range rs.staged {
+		for j, v := range row {
+			c := &rs.data[j]
+			c.buf = append(c.buf, v...)
+			c.ends = append(c.ends, len(c.buf))
+		}
+	}
+	rs.staged = nil
+}
+
+// reset removes all rows, buffers are released rather than reused, so values returned before are still valid.
+func (rs *ResultSet) reset() {
+	rs.data, rs.nrows, rs.staged, rs.nils = nil, 0, nil, rs.nils[:0]
+}
+
+// value returns the value at the i-th row and the j-th column without bound checks, NULL values are nil.
+func (rs *ResultSet) value(i int, j int) []byte {
+	if rs.isNil(i, j) {
+		return nil
+	}
+	if k := i - (rs.nrows - len(rs.staged)); k >= 0 {
+		if v := rs.staged[k][j]; v != nil {
+			return v
+		}
+		return []byte{}
+	}
+	c := &rs.data[j]
+	start, end := 0, c.ends[i]
+	if i > 0 {
+		start = c.ends[i-1]
+	}
+	if start == end {
+		return []byte{}
+	}
+	return c.buf[start:end:end]
+}
+
 func (rs *ResultSet) markNil(i int, j int) {
 	n := i*len(rs.cols) + j
 	for 64*len(rs.nils) <= n {
@@ -353,13 +559,17 @@ func (rs *ResultSet) isNil(i int, j int) bool {
 }
 
 func (rs *ResultSet) encodeCellTo(w io.Writer, i int, j int, f func(i int, j int, raw []byte, def ColumnDef) []byte) error {
-	buf := make([]byte, 4)
-	raw := rs.data[i][j]
+	raw := rs.value(i, j)
 	if f != nil {
 		raw = f(i, j, raw, rs.cols[j])
 	}
+	return writeCell(w, raw, rs.isNil(i, j))
+}
+
+func writeCell(w io.Writer, raw []byte, isNil bool) error {
+	buf := make([]byte, 4)
 	binary.BigEndian.PutUint32(buf, uint32(len(raw)))
-	if rs.isNil(i, j) {
+	if isNil {
 		buf[0] |= 0x80
 	}
 	if _, err := w.Write(buf); err != nil {
@@ -371,6 +581,14 @@ func (rs *ResultSet) encodeCellTo(w io.Writer, i int, j int, f func(i int, j int
 	return nil
 }
 
+func (rs *ResultSet) rowKey(i int, cols []int) string {
+	var buf bytes.Buffer
+	for _, j := range cols {
+		_ = rs.encodeCellTo(&buf, i, j, nil)
+	}
+	return buf.String()
+}
+
 type DigestOptions struct {
 	Sort   bool
 	Filter func(i int, j int, raw []byte, def ColumnDef) bool
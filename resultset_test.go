@@ -3,9 +3,11 @@ package sqlz
 import (
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,18 +34,18 @@ func testDB(t *testing.T) *sql.DB {
 }
 
 var rss = []ResultSet{
-	{nil, nil, nil, ExecResult{0, 0, false, false}},
-	{[]ColumnDef{}, nil, nil, ExecResult{1, 0, true, false}},
-	{[]ColumnDef{
+	*newRawResultSet(nil, nil, nil, ExecResult{0, 0, false, false}),
+	*newRawResultSet([]ColumnDef{}, nil, nil, ExecResult{1, 0, true, false}),
+	*newRawResultSet([]ColumnDef{
 		{Name: "foo", Type: "TEXT"},
-	}, nil, nil, ExecResult{0, 1, false, true}},
-	{[]ColumnDef{
+	}, nil, nil, ExecResult{0, 1, false, true}),
+	*newRawResultSet([]ColumnDef{
 		{Name: "foo", Type: "TEXT"},
 	}, [][][]byte{
 		{{0x1}},
 		{nil},
 		{{}},
-	}, []uint64{2}, ExecResult{1, 1, true, true}},
+	}, []uint64{2}, ExecResult{1, 1, true, true}),
 }
 
 func TestAssertDataNil(t *testing.T) {
@@ -56,32 +58,29 @@ func TestAssertDataNil(t *testing.T) {
 	}
 
 	// nil can match nil & empty string can match empty string
-	rs := ResultSet{cols: []ColumnDef{{Name: "foo", Type: "TEXT"}}, data: [][][]byte{{nil}}}
+	rs := *newRawResultSet([]ColumnDef{{Name: "foo", Type: "TEXT"}}, [][][]byte{{nil}}, nil, ExecResult{})
 	rs.markNil(0, 0)
 	assert.NoError(rs.AssertData(Rows{{nil}}, cb))
 	assert.Equal(0, callCnt)
-	rs = ResultSet{cols: []ColumnDef{{Name: "foo", Type: "TEXT"}}, data: [][][]byte{{[]byte{}}}}
+	rs = *newRawResultSet([]ColumnDef{{Name: "foo", Type: "TEXT"}}, [][][]byte{{[]byte{}}}, nil, ExecResult{})
 	assert.NoError(rs.AssertData(Rows{{""}}, cb))
 	assert.Equal(0, callCnt)
 
 	// nil can't match empty string
-	rs = ResultSet{cols: []ColumnDef{{Name: "foo", Type: "TEXT"}}, data: [][][]byte{{[]byte{}}}}
+	rs = *newRawResultSet([]ColumnDef{{Name: "foo", Type: "TEXT"}}, [][][]byte{{[]byte{}}}, nil, ExecResult{})
 	assert.Error(rs.AssertData(Rows{{nil}}, cb))
 	assert.Equal(1, callCnt)
-	rs = ResultSet{cols: []ColumnDef{{Name: "foo", Type: "TEXT"}}, data: [][][]byte{{nil}}}
+	rs = *newRawResultSet([]ColumnDef{{Name: "foo", Type: "TEXT"}}, [][][]byte{{nil}}, nil, ExecResult{})
 	rs.markNil(0, 0)
 	assert.Error(rs.AssertData(Rows{{""}}, cb))
 	assert.Equal(2, callCnt)
 }
 
 func TestAssertDataFloat(t *testing.T) {
-	rs := ResultSet{
-		cols: []ColumnDef{{Name: "foo", Type: "FLOAT"}},
-		data: [][][]byte{
-			{[]byte("2.7180")},
-			{[]byte("3.1400")},
-		},
-	}
+	rs := newRawResultSet([]ColumnDef{{Name: "foo", Type: "FLOAT"}}, [][][]byte{
+		{[]byte("2.7180")},
+		{[]byte("3.1400")},
+	}, nil, ExecResult{})
 	require.NoError(t, rs.AssertData(Rows{{Float(2.718)}, {Float(3.14)}}))
 	require.NoError(t, rs.AssertData(Rows{{Float(2.72, 0.01)}, {Float(3.15, 0.01)}}))
 	require.Error(t, rs.AssertData(Rows{{Float(2.72, 0.001)}, {Float(3.15, 0.01)}}))
@@ -89,27 +88,18 @@ func TestAssertDataFloat(t *testing.T) {
 }
 
 func TestDataDigest(t *testing.T) {
-	rs1 := ResultSet{
-		cols: []ColumnDef{{Name: "foo", Type: "FLOAT"}},
-		data: [][][]byte{
-			{[]byte("2.718")},
-			{[]byte("3.14")},
-		},
-	}
-	rs2 := ResultSet{
-		cols: []ColumnDef{{Name: "foo", Type: "FLOAT"}},
-		data: [][][]byte{
-			{[]byte("3.141")},
-			{[]byte("2.72")},
-		},
-	}
-	rs3 := ResultSet{
-		cols: []ColumnDef{{Name: "foo", Type: "FLOAT"}},
-		data: [][][]byte{
-			{[]byte("2.7180")},
-			{[]byte("3.1400")},
-		},
-	}
+	rs1 := newRawResultSet([]ColumnDef{{Name: "foo", Type: "FLOAT"}}, [][][]byte{
+		{[]byte("2.718")},
+		{[]byte("3.14")},
+	}, nil, ExecResult{})
+	rs2 := newRawResultSet([]ColumnDef{{Name: "foo", Type: "FLOAT"}}, [][][]byte{
+		{[]byte("3.141")},
+		{[]byte("2.72")},
+	}, nil, ExecResult{})
+	rs3 := newRawResultSet([]ColumnDef{{Name: "foo", Type: "FLOAT"}}, [][][]byte{
+		{[]byte("2.7180")},
+		{[]byte("3.1400")},
+	}, nil, ExecResult{})
 	opts1 := DigestOptions{}
 	opts2 := DigestOptions{Sort: true}
 	require.False(t, rs1.DataDigest(opts1) == rs2.DataDigest(opts1))
@@ -231,3 +221,133 @@ func tEncodeDecodeCheck(rs1 *ResultSet) func(t *testing.T) {
 		}
 	}
 }
+
+func TestColumnByName(t *testing.T) {
+	rs := newRawResultSet(
+		[]ColumnDef{{Name: "id"}, {Name: "Name"}, {Name: "name"}, {Name: "t.x"}, {Name: "v"}, {Name: "V"}},
+		[][][]byte{{[]byte("1"), []byte("a"), []byte("b"), []byte("x"), nil, []byte("")}}, nil, ExecResult{})
+	rs.markNil(0, 4)
+	for _, tt := range []struct {
+		name string
+		idxs []int
+	}{
+		{"id", []int{0}},
+		{"ID", []int{0}},
+		{"t.id", []int{0}},
+		{"db.t.id", []int{0}},
+		{"name", []int{2}},
+		{"NAME", []int{1, 2}},
+		{"t.x", []int{3}},
+		{"x", nil},
+		{"s.v", []int{4}},
+		{"s.v2", nil},
+	} {
+		require.Equal(t, tt.idxs, rs.ColumnIndexes(tt.name), tt.name)
+		idx, err := rs.ColumnIndex(tt.name)
+		switch len(tt.idxs) {
+		case 0:
+			require.True(t, errors.Is(err, ErrColumnNotFound), tt.name)
+		case 1:
+			require.NoError(t, err, tt.name)
+			require.Equal(t, tt.idxs[0], idx, tt.name)
+		default:
+			require.True(t, errors.Is(err, ErrAmbiguousColumn), tt.name)
+		}
+	}
+
+	v, err := rs.ValueByName(0, "t.name")
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), v)
+	v, err = rs.ValueByName(-1, "v")
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = rs.ValueByName(0, "V")
+	require.NoError(t, err)
+	require.Equal(t, []byte{}, v)
+	_, err = rs.ValueByName(1, "id")
+	require.EqualError(t, err, "row index out of range: 1")
+	_, err = rs.ValueByName(0, "foo")
+	require.EqualError(t, err, `column not found: "foo"`)
+	_, err = rs.ValueByName(0, "NAME")
+	require.EqualError(t, err, `ambiguous column: "NAME" matches cols [1 2]`)
+}
+
+func TestSort(t *testing.T) {
+	rs := newTestResultSet([]ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}},
+		[]interface{}{"3", nil},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", ""},
+	)
+	rs.Sort(func(r1 int, r2 int) bool {
+		v1, _ := rs.RawValue(r1, 0)
+		v2, _ := rs.RawValue(r2, 0)
+		return string(v1) < string(v2)
+	})
+	require.NoError(t, rs.AssertData(Rows{{"1", "a"}, {"2", ""}, {"3", nil}}))
+}
+
+func TestMemoryUsage(t *testing.T) {
+	cols := []ColumnDef{{Name: "a", Type: "INT"}, {Name: "b", Type: "INT"}, {Name: "c", Type: "INT"}, {Name: "d", Type: "INT"}}
+	rs := New(cols)
+	empty := rs.MemoryUsage()
+	for i := 0; i < 1000; i++ {
+		row := make([][]byte, len(cols))
+		for j := range row {
+			row[j] = []byte(strconv.Itoa(i % 10))
+		}
+		rs.appendRaw(row)
+	}
+	usage := rs.MemoryUsage()
+	require.Greater(t, usage, empty+4000)
+	// a slice header per cell would take 4000*24 bytes.
+	require.Less(t, usage, 4000*24)
+
+	for i := 0; i < rs.NRows(); i++ {
+		for j := range cols {
+			v, ok := rs.RawValue(i, j)
+			require.True(t, ok)
+			require.Equal(t, strconv.Itoa(i%10), string(v))
+		}
+	}
+}
+
+func TestAllocateRows(t *testing.T) {
+	rs := New([]ColumnDef{{Name: "id", Type: "INT"}, {Name: "name", Type: "VARCHAR"}})
+	r1, r2, r3 := rs.AllocateRow(), rs.AllocateRow(), rs.AllocateRow()
+	*r1[0].(*[]byte), *r1[1].(*[]byte) = []byte("1"), []byte("x")
+	*r2[0].(*[]byte) = []byte("2")
+	rs.markNil(1, 1)
+	*r3[0].(*[]byte), *r3[1].(*[]byte) = []byte("3"), []byte("")
+	require.Equal(t, 3, rs.NRows())
+	require.NoError(t, rs.AssertData(Rows{{"1", "x"}, {"2", nil}, {"3", ""}}))
+
+	r4 := rs.AllocateRow()
+	*r4[0].(*[]byte), *r4[1].(*[]byte) = []byte("4"), []byte("y")
+	v, ok := rs.RawValue(0, 1)
+	require.True(t, ok)
+	require.Equal(t, []byte("x"), v)
+	require.NoError(t, rs.AssertData(Rows{{"1", "x"}, {"2", nil}, {"3", ""}, {"4", "y"}}))
+
+	// rows allocated before reads are still writable after them
+	r5 := rs.AllocateRow()
+	v, _ = rs.RawValue(0, 0)
+	require.Equal(t, []byte("1"), v)
+	*r5[0].(*[]byte), *r5[1].(*[]byte) = []byte("5"), []byte("z")
+	v, _ = rs.RawValue(4, 1)
+	require.Equal(t, []byte("z"), v)
+	*r1[1].(*[]byte) = []byte("w")
+	require.NoError(t, rs.AssertData(Rows{{"1", "w"}, {"2", nil}, {"3", ""}, {"4", "y"}, {"5", "z"}}))
+
+	// reads don't change the result set, so they are safe to run concurrently
+	var wg sync.WaitGroup
+	for k := 0; k < 4; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rs.NRows(); i++ {
+				rs.RawValue(i, 1)
+			}
+		}()
+	}
+	wg.Wait()
+}
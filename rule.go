@@ -0,0 +1,225 @@
+package sqlz
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a ValueChecker which delegates cells satisfying all its conditions to the bound checker, the Match method of
+// the bound checker is not consulted.
+type Rule struct {
+	globs   []string
+	regexps []*regexp.Regexp
+	indexes []int
+	types   []string
+	rows    [][2]int
+	checker ValueChecker
+	err     error
+}
+
+func NewRule() *Rule { return &Rule{} }
+
+// Column restricts the rule to columns whose names match one of the glob patterns, names are case-insensitive.
+func (r *Rule) Column(globs ...string) *Rule {
+	for _, glob := range globs {
+		r.globs = append(r.globs, strings.ToLower(glob))
+	}
+	return r
+}
+
+// ColumnRegexp restricts the rule to columns whose names match the regular expression. An invalid expression is
+// recorded in Err, and the rule matches nothing then.
+func (r *Rule) ColumnRegexp(expr string) *Rule {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return r
+	}
+	r.regexps = append(r.regexps, re)
+	return r
+}
+
+// Index restricts the rule to the columns at the given positions.
+func (r *Rule) Index(idxs ...int) *Rule {
+	r.indexes = append(r.indexes, idxs...)
+	return r
+}
+
+// Type restricts the rule to columns of the given types.
+func (r *Rule) Type(types ...string) *Rule {
+	r.types = append(r.types, types...)
+	return r
+}
+
+// Rows restricts the rule to rows in [from, to), a negative to means no upper bound.
+func (r *Rule) Rows(from int, to int) *Rule {
+	r.rows = append(r.rows, [2]int{from, to})
+	return r
+}
+
+// Use binds the checker to the rule.
+func (r *Rule) Use(checker ValueChecker) *Rule {
+	r.checker = checker
+	return r
+}
+
+// Err returns the first error while building the rule.
+func (r *Rule) Err() error { return r.err }
+
+func (r *Rule) Match(row int, col int, def ColumnDef) bool {
+	if r.checker == nil || r.err != nil {
+		return false
+	}
+	if len(r.globs) > 0 {
+		ok := false
+		for _, glob := range r.globs {
+			if matched, _ := path.Match(glob, strings.ToLower(def.Name)); matched {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(r.regexps) > 0 {
+		ok := false
+		for _, re := range r.regexps {
+			if re.MatchString(def.Name) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(r.indexes) > 0 {
+		ok := false
+		for _, idx := range r.indexes {
+			if idx == col {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(r.types) > 0 && !matchType(def, r.types...) {
+		return false
+	}
+	if len(r.rows) > 0 {
+		ok := false
+		for _, rg := range r.rows {
+			if row >= rg[0] && (rg[1] < 0 || row < rg[1]) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) Equal(v1 []byte, v2 []byte, def ColumnDef) bool { return r.checker.Equal(v1, v2, def) }
+
+// RuleSpec is the declarative form of a Rule.
+type RuleSpec struct {
+	Column  []string    `json:"column,omitempty" yaml:"column,omitempty"`
+	Regexp  []string    `json:"regexp,omitempty" yaml:"regexp,omitempty"`
+	Index   []int       `json:"index,omitempty" yaml:"index,omitempty"`
+	Type    []string    `json:"type,omitempty" yaml:"type,omitempty"`
+	Rows    [][2]int    `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Checker CheckerSpec `json:"checker" yaml:"checker"`
+}
+
+func (s RuleSpec) Build() (*Rule, error) {
+	checker, err := s.Checker.Build()
+	if err != nil {
+		return nil, err
+	}
+	r := NewRule().Column(s.Column...).Index(s.Index...).Type(s.Type...).Use(checker)
+	for _, expr := range s.Regexp {
+		r.ColumnRegexp(expr)
+	}
+	for _, rg := range s.Rows {
+		r.Rows(rg[0], rg[1])
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// CheckerSpec is the declarative form of a builtin checker, supported kinds are raw, ignore, float, decimal, time, json
+// and collation.
+type CheckerSpec struct {
+	Kind      string  `json:"kind" yaml:"kind"`
+	AbsTol    float64 `json:"abs_tol,omitempty" yaml:"abs_tol,omitempty"`
+	RelTol    float64 `json:"rel_tol,omitempty" yaml:"rel_tol,omitempty"`
+	Tolerance string  `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	Collation string  `json:"collation,omitempty" yaml:"collation,omitempty"`
+}
+
+func (s CheckerSpec) Build() (ValueChecker, error) {
+	switch strings.ToLower(s.Kind) {
+	case "raw", "":
+		return RawBytesChecker{}, nil
+	case "ignore":
+		return IgnoreChecker{}, nil
+	case "float":
+		return FloatChecker{AbsTol: s.AbsTol, RelTol: s.RelTol}, nil
+	case "decimal":
+		return DecimalChecker{}, nil
+	case "time":
+		tol := time.Duration(0)
+		if len(s.Tolerance) > 0 {
+			var err error
+			if tol, err = time.ParseDuration(s.Tolerance); err != nil {
+				return nil, err
+			}
+		}
+		return TimeChecker{Tolerance: tol}, nil
+	case "json":
+		return JSONChecker{}, nil
+	case "collation":
+		return CollationChecker{Collation: ParseCollation(s.Collation)}, nil
+	default:
+		return nil, fmt.Errorf("unknown checker kind: %q", s.Kind)
+	}
+}
+
+// LoadRules reads rules from a YAML or JSON document like `{"rules": [{"column": ["*_at"], "checker": {"kind":
+// "time", "tolerance": "1s"}}]}`. The returned checkers end with a RawBytesChecker, so that cells not matched by any
+// rule are compared byte by byte.
+func LoadRules(r io.Reader) ([]ValueChecker, error) {
+	var doc struct {
+		Rules []RuleSpec `json:"rules" yaml:"rules"`
+	}
+	// unknown keys are rejected, since a misspelled condition would make the rule match every column.
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && err != io.EOF {
+		return nil, err
+	}
+	checkers := make([]ValueChecker, 0, len(doc.Rules)+1)
+	for i, spec := range doc.Rules {
+		rule, err := spec.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule#%d: %w", i, err)
+		}
+		checkers = append(checkers, rule)
+	}
+	return append(checkers, RawBytesChecker{}), nil
+}
@@ -0,0 +1,83 @@
+package sqlz
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule(t *testing.T) {
+	def := ColumnDef{Name: "Created_At", Type: "DATETIME"}
+	require.False(t, NewRule().Match(0, 0, def))
+	require.True(t, NewRule().Use(IgnoreChecker{}).Match(0, 0, def))
+	require.True(t, NewRule().Column("*_at").Use(IgnoreChecker{}).Match(0, 0, def))
+	require.False(t, NewRule().Column("*_by").Use(IgnoreChecker{}).Match(0, 0, def))
+	require.True(t, NewRule().ColumnRegexp("_At$").Use(IgnoreChecker{}).Match(0, 0, def))
+	require.False(t, NewRule().ColumnRegexp("_at$").Use(IgnoreChecker{}).Match(0, 0, def))
+	bad := NewRule().ColumnRegexp("(").Use(IgnoreChecker{})
+	require.Error(t, bad.Err())
+	require.False(t, bad.Match(0, 0, def))
+	require.True(t, NewRule().Index(1, 3).Use(IgnoreChecker{}).Match(0, 3, def))
+	require.False(t, NewRule().Index(1, 3).Use(IgnoreChecker{}).Match(0, 2, def))
+	require.True(t, NewRule().Type("DATETIME").Use(IgnoreChecker{}).Match(0, 0, def))
+	require.False(t, NewRule().Type("DATE").Use(IgnoreChecker{}).Match(0, 0, def))
+	require.True(t, NewRule().Rows(2, 4).Use(IgnoreChecker{}).Match(3, 0, def))
+	require.False(t, NewRule().Rows(2, 4).Use(IgnoreChecker{}).Match(4, 0, def))
+	require.True(t, NewRule().Rows(2, -1).Use(IgnoreChecker{}).Match(100, 0, def))
+	require.False(t, NewRule().Column("*_at").Type("DATE").Use(IgnoreChecker{}).Match(0, 0, def))
+
+	rule := NewRule().Column("*_at").Use(TimeChecker{Tolerance: time.Second})
+	require.True(t, rule.Match(0, 0, ColumnDef{Name: "updated_at", Type: "VARCHAR"}))
+	require.True(t, rule.Equal([]byte("2021-01-01 00:00:00"), []byte("2021-01-01 00:00:01"), def))
+}
+
+func TestLoadRules(t *testing.T) {
+	cols := []ColumnDef{{Name: "id", Type: "INT"}, {Name: "price", Type: "DOUBLE"}, {Name: "created_at", Type: "DATETIME"}, {Name: "note", Type: "VARCHAR"}}
+	rs1 := newTestResultSet(cols,
+		[]interface{}{"1", "1.00", "2021-01-01 00:00:00", "foo"},
+		[]interface{}{"2", "2.00", "2021-01-01 00:00:00", "bar"},
+	)
+	rs2 := newTestResultSet(cols,
+		[]interface{}{"1", "1.001", "2021-01-01 00:00:01", "x"},
+		[]interface{}{"2", "2.00", "2021-01-01 00:00:00", "BAR"},
+	)
+
+	for _, doc := range []string{`
+rules:
+- column: ["*_at"]
+  checker: {kind: time, tolerance: 1s}
+- index: [1]
+  checker:
+    kind: float
+    abs_tol: 0.01
+- column: [note]
+  rows: [[0, 1]]
+  checker: {kind: ignore}
+- type: [VARCHAR]
+  checker: {kind: collation, collation: utf8mb4_general_ci}
+`, `{"rules": [
+  {"column": ["*_at"], "checker": {"kind": "time", "tolerance": "1s"}},
+  {"index": [1], "checker": {"kind": "float", "abs_tol": 0.01}},
+  {"column": ["note"], "rows": [[0, 1]], "checker": {"kind": "ignore"}},
+  {"type": ["VARCHAR"], "checker": {"kind": "collation", "collation": "utf8mb4_general_ci"}}
+]}`} {
+		checkers, err := LoadRules(strings.NewReader(doc))
+		require.NoError(t, err)
+		require.Len(t, checkers, 5)
+		require.NoError(t, Diff(rs1, rs2, DiffOptions{ValueCheckers: checkers}))
+		require.Error(t, Diff(rs1, rs2, DiffOptions{ValueCheckers: checkers[1:]}))
+	}
+
+	checkers, err := LoadRules(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, []ValueChecker{RawBytesChecker{}}, checkers)
+	_, err = LoadRules(strings.NewReader(`{"rules": [{"checker": {"kind": "foo"}}]}`))
+	require.EqualError(t, err, `invalid rule#0: unknown checker kind: "foo"`)
+	_, err = LoadRules(strings.NewReader(`{"rules": [{"regexp": ["("]}]}`))
+	require.Error(t, err)
+	_, err = LoadRules(strings.NewReader("rules:\n- colum: [x]\n  checker: {kind: ignore}\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "colum")
+}
@@ -0,0 +1,273 @@
+package sqlz
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType  = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// ScanRow copies the values of the i-th row into dest like sql.Rows.Scan. Besides sql.Scanner, []byte and basic types,
+// dest can be *time.Time and *time.Duration which are parsed from MySQL DATETIME and TIME values, and *interface{}
+// which gets a value of the Go type suggested by Value.
+func (rs *ResultSet) ScanRow(i int, dest ...interface{}) error {
+	if len(dest) != rs.NCols() {
+		return fmt.Errorf("expected %d destination arguments in ScanRow, not %d", rs.NCols(), len(dest))
+	}
+	for j, d := range dest {
+		if err := rs.scanValue(i, j, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanStruct copies the values of the i-th row into the struct pointed by dest. Columns are mapped to fields by `db`
+// tags or by field names case-insensitively, fields tagged by `db:"-"` and columns without fields are skipped.
+func (rs *ResultSet) ScanStruct(i int, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to struct, not %T", dest)
+	}
+	return rs.scanStruct(i, v.Elem(), rs.fieldIndexes(v.Elem().Type()))
+}
+
+// ScanAll appends all rows to the slice pointed by dest. Rows are scanned by ScanStruct if the element type is a struct
+// (or a pointer to struct), or by ScanRow if the result set has only one column.
+func (rs *ResultSet) ScanAll(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("expected a pointer to slice, not %T", dest)
+	}
+	slice := v.Elem()
+	typ := slice.Type().Elem()
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	isStruct := typ.Kind() == reflect.Struct && typ != timeType && !reflect.PtrTo(typ).Implements(scannerType)
+	if !isStruct && rs.NCols() != 1 {
+		return fmt.Errorf("expected 1 column to scan into %s, not %d", typ, rs.NCols())
+	}
+	var fields [][]int
+	if isStruct {
+		fields = rs.fieldIndexes(typ)
+	}
+	for i := 0; i < rs.NRows(); i++ {
+		elem := reflect.New(typ)
+		var err error
+		if isStruct {
+			err = rs.scanStruct(i, elem.Elem(), fields)
+		} else {
+			err = rs.scanValue(i, 0, elem.Interface())
+		}
+		if err != nil {
+			return err
+		}
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	v.Elem().Set(slice)
+	return nil
+}
+
+// Maps returns all rows as maps from column names to values, see Value for the types of values.
+func (rs *ResultSet) Maps() []map[string]interface{} {
+	ms := make([]map[string]interface{}, rs.NRows())
+	for i := range ms {
+		ms[i] = make(map[string]interface{}, rs.NCols())
+		for j, c := range rs.cols {
+			ms[i][c.Name] = rs.Value(i, j)
+		}
+	}
+	return ms
+}
+
+// Value returns the value at (i, j) as a Go value according to the column type: nil for NULL, uint64 for UNSIGNED
+// integers and int64 for other integers, float64 for FLOAT and DOUBLE, []byte for binary strings and BIT, and string for others.
+func (rs *ResultSet) Value(i int, j int) interface{} {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return nil
+	}
+	switch {
+	case matchType(def, "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR"):
+		if strings.HasPrefix(strings.ToUpper(def.Type), "UNSIGNED ") {
+			if x, _, err := rs.Uint64(i, j); err == nil {
+				return x
+			}
+		} else if x, _, err := rs.Int64(i, j); err == nil {
+			return x
+		}
+	case matchType(def, "FLOAT", "DOUBLE", "REAL"):
+		if x, _, err := rs.Float64(i, j); err == nil {
+			return x
+		}
+	case matchType(def, "BIT", "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY"):
+		return append([]byte{}, raw...)
+	}
+	return string(raw)
+}
+
+func (rs *ResultSet) scanStruct(i int, v reflect.Value, fields [][]int) error {
+	for j, idx := range fields {
+		if idx == nil {
+			continue
+		}
+		if err := rs.scanValue(i, j, v.FieldByIndex(idx).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldIndexes maps columns to fields of typ, the result is indexed by columns and nil means no field.
+func (rs *ResultSet) fieldIndexes(typ reflect.Type) [][]int {
+	type field struct {
+		name  string
+		index []int
+		tag   bool
+	}
+	var fields []field
+	var collect func(t reflect.Type, prefix []int)
+	collect = func(t reflect.Type, prefix []int) {
+		for k := 0; k < t.NumField(); k++ {
+			f := t.Field(k)
+			tag, hasTag := f.Tag.Lookup("db")
+			if tag == "-" || len(f.PkgPath) > 0 && !f.Anonymous {
+				continue
+			}
+			index := append(append([]int{}, prefix...), k)
+			if f.Anonymous && !hasTag && f.Type.Kind() == reflect.Struct {
+				collect(f.Type, index)
+				continue
+			}
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			name := f.Name
+			if hasTag && len(tag) > 0 {
+				name = strings.Split(tag, ",")[0]
+			}
+			fields = append(fields, field{name, index, hasTag})
+		}
+	}
+	collect(typ, nil)
+
+	idxs := make([][]int, rs.NCols())
+	for j, c := range rs.cols {
+		for _, f := range fields {
+			if f.tag && f.name == c.Name {
+				idxs[j] = f.index
+				break
+			}
+		}
+		if idxs[j] != nil {
+			continue
+		}
+		for _, f := range fields {
+			if strings.EqualFold(f.name, c.Name) {
+				idxs[j] = f.index
+				break
+			}
+		}
+	}
+	return idxs
+}
+
+func (rs *ResultSet) scanValue(i int, j int, dest interface{}) error {
+	raw, _, ok, err := rs.typedValue(i, j)
+	if err != nil {
+		return err
+	}
+	switch d := dest.(type) {
+	case sql.Scanner:
+		var src interface{}
+		if ok {
+			src = append([]byte{}, raw...)
+		}
+		return rs.convertError(i, j, fmt.Sprintf("%T", dest), d.Scan(src))
+	case *interface{}:
+		*d = rs.Value(i, j)
+		return nil
+	case *[]byte:
+		if ok {
+			*d = append([]byte{}, raw...)
+		} else {
+			*d = nil
+		}
+		return nil
+	case *sql.RawBytes:
+		*d = raw
+		return nil
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("destination not a pointer: %T", dest)
+	}
+	v = v.Elem()
+	if v.Kind() == reflect.Ptr {
+		if !ok {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := rs.scanValue(i, j, p.Interface()); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if !ok {
+		return rs.convertError(i, j, v.Type().String(), errors.New("value is NULL"))
+	}
+
+	var x interface{}
+	switch {
+	case v.Type() == timeType:
+		x, _, err = rs.Time(i, j, nil)
+	case v.Type() == durationType:
+		x, _, err = rs.Duration(i, j)
+	case v.Kind() == reflect.String:
+		x = string(raw)
+	case v.Kind() == reflect.Bool:
+		x, _, err = rs.Bool(i, j)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		var n int64
+		if n, _, err = rs.Int64(i, j); err == nil && v.OverflowInt(n) {
+			err = rs.convertError(i, j, v.Type().String(), errors.New("value out of range"))
+		}
+		x = n
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		var n uint64
+		if n, _, err = rs.Uint64(i, j); err == nil && v.OverflowUint(n) {
+			err = rs.convertError(i, j, v.Type().String(), errors.New("value out of range"))
+		}
+		x = n
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		var f float64
+		if f, _, err = rs.Float64(i, j); err == nil && v.OverflowFloat(f) {
+			err = rs.convertError(i, j, v.Type().String(), errors.New("value out of range"))
+		}
+		x = f
+	default:
+		return fmt.Errorf("unsupported scan destination: %T", dest)
+	}
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(x).Convert(v.Type()))
+	return nil
+}
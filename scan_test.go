@@ -0,0 +1,98 @@
+package sqlz
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT"},
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "score", Type: "DOUBLE"},
+		{Name: "created_at", Type: "DATETIME"},
+		{Name: "data", Type: "BLOB"},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"1", "foo", "1.5", "2021-01-01 00:00:00", []byte{0}},
+		[]interface{}{"2", nil, nil, nil, nil},
+	)
+
+	var (
+		id    int
+		name  sql.NullString
+		score *float64
+		ts    time.Time
+		data  []byte
+	)
+	require.NoError(t, rs.ScanRow(0, &id, &name, &score, &ts, &data))
+	require.Equal(t, 1, id)
+	require.Equal(t, sql.NullString{String: "foo", Valid: true}, name)
+	require.Equal(t, 1.5, *score)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), ts)
+	require.Equal(t, []byte{0}, data)
+	require.NoError(t, rs.ScanRow(1, &id, &name, &score, new(interface{}), &data))
+	require.Equal(t, 2, id)
+	require.False(t, name.Valid)
+	require.Nil(t, score)
+	require.Nil(t, data)
+	require.EqualError(t, rs.ScanRow(1, &id, new(string), &score, &ts, &data), `convert ("name"#1) "" of type VARCHAR to string: value is NULL`)
+	require.EqualError(t, rs.ScanRow(0, &id), "expected 5 destination arguments in ScanRow, not 1")
+	var i8 int8
+	require.NoError(t, rs.ScanRow(0, &i8, new(string), new(float32), new(interface{}), new(string)))
+	require.Error(t, rs.ScanRow(0, &id, &id, &score, &ts, &data))
+
+	type Base struct {
+		ID int64 `db:"id"`
+	}
+	type Record struct {
+		Base
+		Name    *string
+		Score   sql.NullFloat64
+		Created time.Time `db:"created_at"`
+		Ignored []byte    `db:"-"`
+		Extra   int
+		hidden  string
+	}
+	var r Record
+	require.NoError(t, rs.ScanStruct(0, &r))
+	require.Equal(t, int64(1), r.ID)
+	require.Equal(t, "foo", *r.Name)
+	require.Equal(t, sql.NullFloat64{Float64: 1.5, Valid: true}, r.Score)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), r.Created)
+	require.Nil(t, r.Ignored)
+	require.Error(t, rs.ScanStruct(0, r))
+	require.Error(t, rs.ScanStruct(1, &r))
+
+	var rs2 []*Record
+	require.Error(t, rs.ScanAll(&rs2))
+	rs = newTestResultSet(cols[:3],
+		[]interface{}{"1", "foo", "1.5"},
+		[]interface{}{"2", nil, nil},
+	)
+	require.NoError(t, rs.ScanAll(&rs2))
+	require.Len(t, rs2, 2)
+	require.Equal(t, int64(2), rs2[1].ID)
+	require.Nil(t, rs2[1].Name)
+	var rs3 []Record
+	require.NoError(t, rs.ScanAll(&rs3))
+	require.Equal(t, "foo", *rs3[0].Name)
+
+	var ids []int64
+	require.Error(t, rs.ScanAll(&ids))
+	rs = newTestResultSet(cols[:1], []interface{}{"1"}, []interface{}{"3"})
+	require.NoError(t, rs.ScanAll(&ids))
+	require.Equal(t, []int64{1, 3}, ids)
+
+	rs = newTestResultSet(append(cols, ColumnDef{Name: "u", Type: "UNSIGNED BIGINT"}),
+		[]interface{}{"1", "foo", "1.5", "2021-01-01 00:00:00", []byte{0}, "18446744073709551615"},
+		[]interface{}{"2", nil, nil, nil, nil, "0"},
+	)
+	require.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "foo", "score": 1.5, "created_at": "2021-01-01 00:00:00", "data": []byte{0}, "u": uint64(18446744073709551615)},
+		{"id": int64(2), "name": nil, "score": nil, "created_at": nil, "data": nil, "u": uint64(0)},
+	}, rs.Maps())
+}
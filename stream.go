@@ -0,0 +1,216 @@
+package sqlz
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sort"
+)
+
+// EncodeRows streams rows to w in the versioned binary format without materializing them, rows are buffered and
+// written in chunks of opts.ChunkRows. The output is readable by DecodeFrom and NewDecoder.
+func EncodeRows(w io.Writer, rows RowIterator, opts EncodeOptions) error {
+	defer rows.Close()
+	cols, err := readColumnDefs(rows)
+	if err != nil {
+		return err
+	}
+	fw, err := newFrameWriter(w, opts.Codec)
+	if err != nil {
+		return err
+	}
+	if err = fw.writeColumns(cols); err != nil {
+		return err
+	}
+	chunk, n := New(cols), opts.chunkRows()
+	for rows.Next() {
+		if err = chunk.scanRow(rows); err != nil {
+			return err
+		}
+		if chunk.NRows() >= n {
+			if err = fw.writeRows(chunk, 0, chunk.NRows()); err != nil {
+				return err
+			}
+			chunk.reset()
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if chunk.NRows() > 0 {
+		if err = fw.writeRows(chunk, 0, chunk.NRows()); err != nil {
+			return err
+		}
+	}
+	return fw.close()
+}
+
+// Decoder reads rows incrementally from the output of EncodeRows or EncodeTo, only one chunk of rows is kept in memory.
+// The legacy gob format is also accepted, but it's decoded at once.
+type Decoder struct {
+	fr    *frameReader
+	cols  []ColumnDef
+	exec  ExecResult
+	chunk *ResultSet
+	pos   int
+	err   error
+}
+
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(formatMagic))
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	d := &Decoder{chunk: &ResultSet{}}
+	if string(magic) != formatMagic {
+		if err = d.chunk.DecodeFrom(br); err != nil {
+			return nil, err
+		}
+		d.cols, d.exec = d.chunk.cols, d.chunk.exec
+		return d, nil
+	}
+	if d.fr, err = newFrameReader(br); err != nil {
+		return nil, err
+	}
+	// read sections until the columns, so that they are available before the first row.
+	for d.fr != nil && d.cols == nil {
+		if err = d.readSection(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Columns returns the column definitions, which are empty for exec results.
+func (d *Decoder) Columns() []ColumnDef { return d.cols }
+
+func (d *Decoder) ExecResult() ExecResult { return d.exec }
+
+// Next advances to the next row, it returns false at the end or on error.
+func (d *Decoder) Next() bool {
+	for d.err == nil {
+		if d.pos < d.chunk.NRows() {
+			d.pos++
+			return true
+		}
+		if d.fr == nil {
+			return false
+		}
+		d.err = d.readSection()
+	}
+	return false
+}
+
+// Row returns the current row where NULL values are nil, it's valid until the next call of Next.
+func (d *Decoder) Row() [][]byte { return d.chunk.rawRow(d.pos - 1) }
+
+func (d *Decoder) Err() error { return d.err }
+
+// Rows returns the remaining rows as ResultRows, rows are read from the decoder along with the iteration.
+func (d *Decoder) Rows() *ResultRows {
+	return &ResultRows{cols: d.cols, err: d.Err, next: func() (*ResultSet, int, bool) {
+		// Next must come first since it may replace the current chunk.
+		ok := d.Next()
+		return d.chunk, d.pos - 1, ok
+	}}
+}
+
+func (d *Decoder) readSection() error {
+	tag, payload, err := d.fr.next()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case sectionEnd:
+		err = d.fr.close()
+		d.fr = nil
+	case sectionColumns:
+		d.cols, err = decodeColumns(payload)
+	case sectionExec:
+		d.exec, err = decodeExec(payload)
+	case sectionRows:
+		if d.cols == nil {
+			err = errRowsBeforeColumns
+		} else {
+			d.chunk, d.pos = New(d.cols), 0
+			err = d.chunk.decodeRows(payload)
+		}
+	}
+	return sectionError(tag, err)
+}
+
+// Digester computes the same digest as DataDigest from rows added one by one, so that it works on streams. Only the
+// digest of each row is kept if opts.Sort is set.
+type Digester struct {
+	cols    []ColumnDef
+	opts    DigestOptions
+	h       hash.Hash
+	digests [][]byte
+	n       int
+}
+
+func NewDigester(cols []ColumnDef, opts DigestOptions) *Digester {
+	return &Digester{cols: cols, opts: opts, h: sha1.New()}
+}
+
+// Add adds a row where NULL values are nil.
+func (d *Digester) Add(row [][]byte) {
+	h := d.h
+	if d.opts.Sort {
+		h = sha1.New()
+	}
+	for j, v := range row {
+		if d.opts.Filter != nil && !d.opts.Filter(d.n, j, v, d.cols[j]) {
+			continue
+		}
+		raw := v
+		if d.opts.Mapper != nil {
+			raw = d.opts.Mapper(d.n, j, v, d.cols[j])
+		}
+		_ = writeCell(h, raw, v == nil)
+	}
+	if d.opts.Sort {
+		d.digests = append(d.digests, h.Sum(nil))
+	}
+	d.n++
+}
+
+func (d *Digester) Sum() string {
+	if len(d.cols) == 0 {
+		return ""
+	}
+	if !d.opts.Sort {
+		return hex.EncodeToString(d.h.Sum(nil))
+	}
+	sort.Slice(d.digests, func(i, j int) bool {
+		return bytes.Compare(d.digests[i], d.digests[j]) < 0
+	})
+	h := sha1.New()
+	for _, digest := range d.digests {
+		h.Write(digest)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DigestRows computes DataDigest of rows without materializing them.
+func DigestRows(rows RowIterator, opts DigestOptions) (string, error) {
+	defer rows.Close()
+	cols, err := readColumnDefs(rows)
+	if err != nil {
+		return "", err
+	}
+	d := NewDigester(cols, opts)
+	row := New(cols)
+	for rows.Next() {
+		if err = row.scanRow(rows); err != nil {
+			return "", err
+		}
+		d.Add(row.rawRow(0))
+		row.reset()
+	}
+	return d.Sum(), rows.Err()
+}
@@ -0,0 +1,96 @@
+package sqlz
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamEncodeDecode(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "id", Type: "BIGINT", Nullable: false, HasNullable: true},
+		{Name: "name", Type: "VARCHAR", Length: 64, HasLength: true},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"3", "foo"},
+		[]interface{}{"1", nil},
+		[]interface{}{"2", ""},
+		[]interface{}{"5", "bar"},
+		[]interface{}{"4", nil},
+	)
+	opts := []DigestOptions{
+		{},
+		{Sort: true},
+		{Filter: func(i int, j int, raw []byte, def ColumnDef) bool { return j == 1 }},
+		{Mapper: func(i int, j int, raw []byte, def ColumnDef) []byte { return bytes.ToUpper(raw) }},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeRows(&buf, rs.Rows(), EncodeOptions{Codec: CodecGzip, ChunkRows: 2}))
+	raw := append([]byte{}, buf.Bytes()...)
+
+	rs2 := &ResultSet{}
+	require.NoError(t, rs2.Decode(raw))
+	require.Equal(t, rs.cols, rs2.cols)
+	require.Equal(t, rs.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+
+	d, err := NewDecoder(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, cols, d.Columns())
+	digesters := make([]*Digester, len(opts))
+	for k := range opts {
+		digesters[k] = NewDigester(d.Columns(), opts[k])
+	}
+	n := 0
+	for d.Next() {
+		require.Equal(t, rs.rawRow(n), d.Row())
+		for _, dg := range digesters {
+			dg.Add(d.Row())
+		}
+		n++
+	}
+	require.NoError(t, d.Err())
+	require.Equal(t, rs.NRows(), n)
+	for k := range opts {
+		require.Equal(t, rs.DataDigest(opts[k]), digesters[k].Sum())
+		digest, err := DigestRows(rs.Rows(), opts[k])
+		require.NoError(t, err)
+		require.Equal(t, rs.DataDigest(opts[k]), digest)
+	}
+
+	d, err = NewDecoder(bytes.NewReader(raw))
+	require.NoError(t, err)
+	rs2, err = ReadFromRows(d.Rows())
+	require.NoError(t, err)
+	require.Equal(t, rs.cols, rs2.cols)
+	require.Equal(t, rs.DataDigest(DigestOptions{}), rs2.DataDigest(DigestOptions{}))
+
+	raw[len(raw)-1] ^= 0xff
+	d, err = NewDecoder(bytes.NewReader(raw))
+	require.NoError(t, err)
+	for d.Next() {
+	}
+	require.EqualError(t, d.Err(), "checksum mismatch")
+
+	buf.Reset()
+	require.NoError(t, rs.encodeLegacy(&buf))
+	d, err = NewDecoder(&buf)
+	require.NoError(t, err)
+	require.Equal(t, cols, d.Columns())
+	for n = 0; d.Next(); n++ {
+		require.Equal(t, rs.rawRow(n), d.Row())
+	}
+	require.Equal(t, rs.NRows(), n)
+
+	buf.Reset()
+	exec := ExecResult{RowsAffected: 1, LastInsertId: 2, HasRowsAffected: true, HasLastInsertId: true}
+	require.NoError(t, NewFromResult(execResult{exec}).EncodeTo(&buf))
+	d, err = NewDecoder(&buf)
+	require.NoError(t, err)
+	require.Empty(t, d.Columns())
+	require.Equal(t, exec, d.ExecResult())
+	require.False(t, d.Next())
+	require.NoError(t, d.Err())
+	require.Equal(t, "", NewDigester(d.Columns(), DigestOptions{}).Sum())
+}
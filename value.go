@@ -0,0 +1,242 @@
+package sqlz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConvertError reports a raw value which can not be converted to the requested type.
+type ConvertError struct {
+	Row int
+	Col int
+	Def ColumnDef
+	Raw []byte
+	To  string
+	Err error
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("convert (%q#%d) %q of type %s to %s: %v", e.Def.Name, e.Row, e.Raw, e.Def.Type, e.To, e.Err)
+}
+
+func (e *ConvertError) Unwrap() error { return e.Err }
+
+// IsNull reports whether the value at (i, j) is NULL.
+func (rs *ResultSet) IsNull(i int, j int) bool {
+	if i < 0 {
+		i += rs.NRows()
+	}
+	if j < 0 {
+		j += rs.NCols()
+	}
+	if i < 0 || i >= rs.NRows() || j < 0 || j >= rs.NCols() {
+		return false
+	}
+	return rs.isNil(i, j)
+}
+
+// Int64 returns the value at (i, j) as an int64, the second return value is false if it's NULL. BIT values are decoded
+// as big-endian integers.
+func (rs *ResultSet) Int64(i int, j int) (int64, bool, error) {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	if matchType(def, "BIT") {
+		x, err := parseBits(raw)
+		if err == nil && x > 1<<63-1 {
+			err = strconv.ErrRange
+		}
+		return int64(x), true, rs.convertError(i, j, "int64", err)
+	}
+	x, err := strconv.ParseInt(string(raw), 10, 64)
+	return x, true, rs.convertError(i, j, "int64", err)
+}
+
+// Uint64 is like Int64 but returns an uint64.
+func (rs *ResultSet) Uint64(i int, j int) (uint64, bool, error) {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	if matchType(def, "BIT") {
+		x, err := parseBits(raw)
+		return x, true, rs.convertError(i, j, "uint64", err)
+	}
+	x, err := strconv.ParseUint(string(raw), 10, 64)
+	return x, true, rs.convertError(i, j, "uint64", err)
+}
+
+// Float64 returns the value at (i, j) as a float64, the second return value is false if it's NULL.
+func (rs *ResultSet) Float64(i int, j int) (float64, bool, error) {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	if matchType(def, "BIT") {
+		x, err := parseBits(raw)
+		return float64(x), true, rs.convertError(i, j, "float64", err)
+	}
+	x, err := strconv.ParseFloat(string(raw), 64)
+	return x, true, rs.convertError(i, j, "float64", err)
+}
+
+// Bool returns the value at (i, j) as a bool, numbers are true if they are not zero.
+func (rs *ResultSet) Bool(i int, j int) (bool, bool, error) {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return false, ok, err
+	}
+	if matchType(def, "BIT") {
+		x, err := parseBits(raw)
+		return x != 0, true, rs.convertError(i, j, "bool", err)
+	}
+	if b, err := strconv.ParseBool(string(raw)); err == nil {
+		return b, true, nil
+	}
+	x, err := strconv.ParseFloat(string(raw), 64)
+	return x != 0, true, rs.convertError(i, j, "bool", err)
+}
+
+// Decimal returns the value at (i, j) as a decimal string, which is validated but kept as is to preserve precision.
+func (rs *ResultSet) Decimal(i int, j int) (string, bool, error) {
+	raw, _, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return "", ok, err
+	}
+	if !isDecimal(string(raw)) {
+		return "", true, rs.convertError(i, j, "decimal", strconv.ErrSyntax)
+	}
+	return string(raw), true, nil
+}
+
+// Time returns the value at (i, j) as a time.Time in loc (UTC if loc is nil). DATE values are parsed as midnight, and
+// zero dates like 0000-00-00 are returned as the zero time.Time.
+func (rs *ResultSet) Time(i int, j int, loc *time.Location) (time.Time, bool, error) {
+	raw, def, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return time.Time{}, ok, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := string(raw)
+	if strings.HasPrefix(s, "0000-00-00") && strings.Trim(s, "0-: .") == "" {
+		return time.Time{}, true, nil
+	}
+	layout := mysqlTimeLayout
+	if matchType(def, "DATE") || len(s) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	return t, true, rs.convertError(i, j, "time", err)
+}
+
+// Duration returns the value at (i, j) as a time.Duration, it accepts MySQL TIME values like -838:59:59.000000.
+func (rs *ResultSet) Duration(i int, j int) (time.Duration, bool, error) {
+	raw, _, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	d, err := parseMySQLTime(string(raw))
+	return d, true, rs.convertError(i, j, "duration", err)
+}
+
+// JSON unmarshals the value at (i, j) into v, the first return value is false if it's NULL.
+func (rs *ResultSet) JSON(i int, j int, v interface{}) (bool, error) {
+	raw, _, ok, err := rs.typedValue(i, j)
+	if !ok || err != nil {
+		return ok, err
+	}
+	return true, rs.convertError(i, j, "json", json.Unmarshal(raw, v))
+}
+
+func (rs *ResultSet) typedValue(i int, j int) ([]byte, ColumnDef, bool, error) {
+	if i < 0 {
+		i += rs.NRows()
+	}
+	if j < 0 {
+		j += rs.NCols()
+	}
+	if i < 0 || i >= rs.NRows() || j < 0 || j >= rs.NCols() {
+		return nil, ColumnDef{}, false, fmt.Errorf("cell index out of range: (%d, %d)", i, j)
+	}
+	if rs.isNil(i, j) {
+		return nil, rs.cols[j], false, nil
+	}
+	raw, _ := rs.RawValue(i, j)
+	return raw, rs.cols[j], true, nil
+}
+
+func (rs *ResultSet) convertError(i int, j int, to string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if i < 0 {
+		i += rs.NRows()
+	}
+	if j < 0 {
+		j += rs.NCols()
+	}
+	raw, _ := rs.RawValue(i, j)
+	return &ConvertError{Row: i, Col: j, Def: rs.cols[j], Raw: raw, To: to, Err: err}
+}
+
+func isDecimal(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	digits, dot := 0, false
+	for _, c := range s {
+		if c == '.' && !dot {
+			dot = true
+		} else if c >= '0' && c <= '9' {
+			digits++
+		} else {
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func parseBits(raw []byte) (uint64, error) {
+	if len(raw) > 8 {
+		return 0, strconv.ErrRange
+	}
+	var x uint64
+	for _, b := range raw {
+		x = x<<8 | uint64(b)
+	}
+	return x, nil
+}
+
+func parseMySQLTime(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	parts := strings.Split(strings.TrimPrefix(s, "-"), ":")
+	if len(parts) != 3 {
+		return 0, strconv.ErrSyntax
+	}
+	var frac string
+	if pos := strings.IndexByte(parts[2], '.'); pos >= 0 {
+		parts[2], frac = parts[2][:pos], parts[2][pos+1:]
+	}
+	h, err1 := strconv.ParseUint(parts[0], 10, 32)
+	m, err2 := strconv.ParseUint(parts[1], 10, 8)
+	sec, err3 := strconv.ParseUint(parts[2], 10, 8)
+	if err1 != nil || err2 != nil || err3 != nil || m >= 60 || sec >= 60 || len(frac) > 9 {
+		return 0, strconv.ErrSyntax
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	if len(frac) > 0 {
+		ns, err := strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return 0, strconv.ErrSyntax
+		}
+		d += time.Duration(ns)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,101 @@
+package sqlz
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedValues(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "i", Type: "BIGINT"},
+		{Name: "u", Type: "UNSIGNED BIGINT"},
+		{Name: "f", Type: "DOUBLE"},
+		{Name: "b", Type: "BIT"},
+		{Name: "d", Type: "DECIMAL"},
+		{Name: "dt", Type: "DATETIME"},
+		{Name: "day", Type: "DATE"},
+		{Name: "t", Type: "TIME"},
+		{Name: "j", Type: "JSON"},
+		{Name: "s", Type: "VARCHAR"},
+	}
+	rs := newTestResultSet(cols,
+		[]interface{}{"-42", "18446744073709551615", "3.14", []byte{0x01, 0x00}, "-12.340", "2021-02-03 04:05:06.789", "2021-02-03", "-838:59:59.5", `{"a":[1,2]}`, "true"},
+		[]interface{}{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+		[]interface{}{"x", "-1", "pi", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, "1e3", "yesterday", "0000-00-00", "12:60:00", `{`, "maybe"},
+	)
+
+	i64, ok, err := rs.Int64(0, 0)
+	require.Equal(t, []interface{}{int64(-42), true, nil}, []interface{}{i64, ok, err})
+	u64, ok, err := rs.Uint64(0, 1)
+	require.Equal(t, []interface{}{uint64(18446744073709551615), true, nil}, []interface{}{u64, ok, err})
+	f64, ok, err := rs.Float64(0, 2)
+	require.Equal(t, []interface{}{3.14, true, nil}, []interface{}{f64, ok, err})
+	i64, ok, err = rs.Int64(0, 3)
+	require.Equal(t, []interface{}{int64(256), true, nil}, []interface{}{i64, ok, err})
+	b, ok, err := rs.Bool(0, 3)
+	require.Equal(t, []interface{}{true, true, nil}, []interface{}{b, ok, err})
+	b, ok, err = rs.Bool(0, 9)
+	require.Equal(t, []interface{}{true, true, nil}, []interface{}{b, ok, err})
+	b, ok, err = rs.Bool(0, 0)
+	require.Equal(t, []interface{}{true, true, nil}, []interface{}{b, ok, err})
+	dec, ok, err := rs.Decimal(0, 4)
+	require.Equal(t, []interface{}{"-12.340", true, nil}, []interface{}{dec, ok, err})
+	loc := time.FixedZone("UTC+8", 8*3600)
+	ts, ok, err := rs.Time(0, 5, loc)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, time.Date(2021, 2, 3, 4, 5, 6, 789000000, loc).Equal(ts))
+	ts, ok, err = rs.Time(0, 6, nil)
+	require.Equal(t, []interface{}{time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC), true, nil}, []interface{}{ts, ok, err})
+	dur, ok, err := rs.Duration(0, 7)
+	require.Equal(t, []interface{}{-(838*time.Hour + 59*time.Minute + 59*time.Second + 500*time.Millisecond), true, nil}, []interface{}{dur, ok, err})
+	var doc struct{ A []int }
+	ok, err = rs.JSON(0, 8, &doc)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []int{1, 2}, doc.A)
+
+	for j := range cols {
+		require.True(t, rs.IsNull(1, j))
+		require.False(t, rs.IsNull(0, j))
+		_, ok, err := rs.Int64(1, j)
+		require.False(t, ok)
+		require.NoError(t, err)
+		_, ok, err = rs.Time(1, j, nil)
+		require.False(t, ok)
+		require.NoError(t, err)
+		ok, err = rs.JSON(1, j, &doc)
+		require.False(t, ok)
+		require.NoError(t, err)
+	}
+
+	ts, ok, err = rs.Time(2, 6, nil)
+	require.Equal(t, []interface{}{time.Time{}, true, nil}, []interface{}{ts, ok, err})
+	for _, f := range []func() error{
+		func() error { _, _, err := rs.Int64(2, 0); return err },
+		func() error { _, _, err := rs.Uint64(2, 1); return err },
+		func() error { _, _, err := rs.Float64(2, 2); return err },
+		func() error { _, _, err := rs.Int64(2, 3); return err },
+		func() error { _, _, err := rs.Decimal(2, 4); return err },
+		func() error { _, _, err := rs.Time(2, 5, nil); return err },
+		func() error { _, _, err := rs.Duration(2, 7); return err },
+		func() error { _, err := rs.JSON(2, 8, &doc); return err },
+		func() error { _, _, err := rs.Bool(2, 9); return err },
+	} {
+		var ce *ConvertError
+		err := f()
+		require.True(t, errors.As(err, &ce), "%v", err)
+		require.Equal(t, 2, ce.Row)
+		require.Contains(t, err.Error(), "(\""+ce.Def.Name+"\"#2)")
+	}
+	_, _, err = rs.Int64(0, 2)
+	require.EqualError(t, err, `convert ("f"#0) "3.14" of type DOUBLE to int64: strconv.ParseInt: parsing "3.14": invalid syntax`)
+	_, _, err = rs.Int64(-1, 3)
+	require.True(t, errors.Is(err, strconv.ErrRange))
+	_, _, err = rs.Int64(3, 0)
+	require.EqualError(t, err, "cell index out of range: (3, 0)")
+}